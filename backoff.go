@@ -0,0 +1,152 @@
+package gottp
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BackoffKind selects one of the built in backoff strategies
+type BackoffKind int
+
+const (
+	// ExponentialBackoff doubles the wait after every attempt
+	ExponentialBackoff BackoffKind = iota
+	// ConstantBackoff waits InitialBackoff between every attempt
+	ConstantBackoff
+	// LinearBackoff grows the wait by InitialBackoff after every attempt
+	LinearBackoff
+	// FullJitterBackoff waits a random duration up to the exponential backoff
+	FullJitterBackoff
+	// DecorrelatedJitterBackoff waits a random duration between
+	// InitialBackoff and three times the previous wait
+	DecorrelatedJitterBackoff
+)
+
+// newBackoffStrategy the strategy configured by the retry policy, the custom
+// strategy takes precedence over the selected kind
+func newBackoffStrategy(policy RetryPolicy) BackoffStrategy {
+	if policy.BackoffStrategy != nil {
+		return policy.BackoffStrategy
+	}
+	base := backoffBase{
+		initial: policy.InitialBackoff,
+		max:     policy.MaxBackoff,
+	}
+	switch policy.Backoff {
+	case ConstantBackoff:
+		return constantBackoff{base}
+	case LinearBackoff:
+		return linearBackoff{base}
+	case FullJitterBackoff:
+		return fullJitterBackoff{base}
+	case DecorrelatedJitterBackoff:
+		return decorrelatedJitterBackoff{base}
+	default:
+		return exponentialBackoff{base}
+	}
+}
+
+type backoffBase struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+func (base backoffBase) cap(delay time.Duration) time.Duration {
+	if base.max > 0 && delay > base.max {
+		return base.max
+	}
+	return delay
+}
+
+// exponential initial * 2^(attempt-1) without overflowing
+func (base backoffBase) exponential(attempt int) time.Duration {
+	delay := base.initial
+	for idx := 1; idx < attempt; idx++ {
+		if delay > time.Duration(1<<62)/2 {
+			return time.Duration(1<<63 - 1)
+		}
+		delay *= 2
+		if base.max > 0 && delay > base.max {
+			return base.max
+		}
+	}
+	return delay
+}
+
+type constantBackoff struct {
+	backoffBase
+}
+
+func (strategy constantBackoff) Backoff(
+	attempt int,
+	previous time.Duration,
+) time.Duration {
+	return strategy.cap(strategy.initial)
+}
+
+type linearBackoff struct {
+	backoffBase
+}
+
+func (strategy linearBackoff) Backoff(
+	attempt int,
+	previous time.Duration,
+) time.Duration {
+	return strategy.cap(strategy.initial * time.Duration(attempt))
+}
+
+type exponentialBackoff struct {
+	backoffBase
+}
+
+func (strategy exponentialBackoff) Backoff(
+	attempt int,
+	previous time.Duration,
+) time.Duration {
+	return strategy.cap(strategy.exponential(attempt))
+}
+
+type fullJitterBackoff struct {
+	backoffBase
+}
+
+func (strategy fullJitterBackoff) Backoff(
+	attempt int,
+	previous time.Duration,
+) time.Duration {
+	return randomBetween(0, strategy.cap(strategy.exponential(attempt)))
+}
+
+type decorrelatedJitterBackoff struct {
+	backoffBase
+}
+
+func (strategy decorrelatedJitterBackoff) Backoff(
+	attempt int,
+	previous time.Duration,
+) time.Duration {
+	if previous < strategy.initial {
+		previous = strategy.initial
+	}
+	upper := previous * 3
+	if upper < previous {
+		upper = previous
+	}
+	return strategy.cap(randomBetween(strategy.initial, upper))
+}
+
+var (
+	backoffRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	backoffRandMu sync.Mutex
+)
+
+// randomBetween a random duration in [low, high]
+func randomBetween(low time.Duration, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	backoffRandMu.Lock()
+	defer backoffRandMu.Unlock()
+	return low + time.Duration(backoffRand.Int63n(int64(high-low)+1))
+}
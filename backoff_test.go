@@ -0,0 +1,51 @@
+package gottp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	policy.Backoff = ConstantBackoff
+	if delay := newBackoffStrategy(policy).Backoff(4, 0); delay != 100*time.Millisecond {
+		t.Errorf("constant backoff returned %v", delay)
+	}
+
+	policy.Backoff = LinearBackoff
+	if delay := newBackoffStrategy(policy).Backoff(3, 0); delay != 300*time.Millisecond {
+		t.Errorf("linear backoff returned %v", delay)
+	}
+
+	policy.Backoff = ExponentialBackoff
+	strategy := newBackoffStrategy(policy)
+	if delay := strategy.Backoff(3, 0); delay != 400*time.Millisecond {
+		t.Errorf("exponential backoff returned %v", delay)
+	}
+	if delay := strategy.Backoff(100, 0); delay != time.Second {
+		t.Errorf("exponential backoff not capped, returned %v", delay)
+	}
+
+	policy.Backoff = FullJitterBackoff
+	strategy = newBackoffStrategy(policy)
+	for attempt := 1; attempt < 50; attempt++ {
+		if delay := strategy.Backoff(attempt, 0); delay < 0 || delay > time.Second {
+			t.Errorf("full jitter backoff out of range %v", delay)
+		}
+	}
+
+	policy.Backoff = DecorrelatedJitterBackoff
+	strategy = newBackoffStrategy(policy)
+	previous := time.Duration(0)
+	for attempt := 1; attempt < 50; attempt++ {
+		delay := strategy.Backoff(attempt, previous)
+		if delay < 100*time.Millisecond || delay > time.Second {
+			t.Errorf("decorrelated jitter backoff out of range %v", delay)
+		}
+		previous = delay
+	}
+}
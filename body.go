@@ -0,0 +1,134 @@
+package gottp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// bodyFactory creates a fresh reader over a request body, it is invoked for
+// every attempt so retries resend the complete payload
+type bodyFactory func() (io.ReadCloser, error)
+
+// bytesBody replayable body over an in memory payload
+func bytesBody(byts []byte) bodyFactory {
+	return func() (io.ReadCloser, error) {
+		return memoryBody{bytes.NewReader(byts)}, nil
+	}
+}
+
+// memoryBody in memory body, every reader created by bytesBody is
+// independent so copies of the request can be sent concurrently
+type memoryBody struct {
+	*bytes.Reader
+}
+
+func (memoryBody) Close() error {
+	return nil
+}
+
+// newBodyRequest creates a request carrying a replayable in memory body
+func newBodyRequest(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	byts []byte,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = setBody(req, bytesBody(byts), int64(len(byts)))
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// setBody attaches the body to the request and populates GetBody so the
+// request can be cloned with a fresh body for every attempt
+func setBody(
+	req *http.Request,
+	factory bodyFactory,
+	length int64,
+) error {
+	if length == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		req.ContentLength = 0
+		return nil
+	}
+	body, err := factory()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = factory
+	req.ContentLength = length
+	return nil
+}
+
+// setStreamBody attaches a streamed body of the length, -1 when unknown, and
+// only populates GetBody when the stream can be re-created for a retry
+func setStreamBody(
+	req *http.Request,
+	factory bodyFactory,
+	length int64,
+	replayable bool,
+) error {
+	if err := setBody(req, factory, length); err != nil {
+		return err
+	}
+	if !replayable {
+		req.GetBody = nil
+	}
+	return nil
+}
+
+// closeBody closes the body of a request that is given up before reaching
+// the transport, as the transport would have, so streamed bodies stop their
+// writers and release the readers handed in by the caller
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// canReplay reports whether the request's body can be sent again
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// canSendConcurrently reports whether copies of the request can be in flight
+// at the same time, which streamed bodies sharing one reader can not
+func canSendConcurrently(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	_, ok := req.Body.(memoryBody)
+	return ok
+}
+
+// newAttempt clones the request for a single attempt bound to the attempt's
+// context, the first attempt consumes the original body and every following
+// attempt rewinds it through GetBody
+func newAttempt(
+	ctx context.Context,
+	req *http.Request,
+	attempt int,
+) (*http.Request, error) {
+	areq := req.Clone(ctx)
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return areq, nil
+	}
+	if req.GetBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	areq.Body = body
+	return areq, nil
+}
@@ -0,0 +1,194 @@
+package gottp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (state circuitState) String() string {
+	switch state {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreakers the circuit breakers of a client keyed by host
+type circuitBreakers struct {
+	policy CircuitBreakerPolicy
+	mtx    sync.Mutex
+	hosts  map[string]*circuitBreaker
+}
+
+func newCircuitBreakers(policy CircuitBreakerPolicy) *circuitBreakers {
+	if !policy.Enabled {
+		return nil
+	}
+	return &circuitBreakers{
+		policy: policy,
+		hosts:  map[string]*circuitBreaker{},
+	}
+}
+
+// get the breaker of the host, nil when circuit breaking is disabled
+func (breakers *circuitBreakers) get(host string) *circuitBreaker {
+	if breakers == nil {
+		return nil
+	}
+	breakers.mtx.Lock()
+	defer breakers.mtx.Unlock()
+	breaker, ok := breakers.hosts[host]
+	if !ok {
+		breaker = &circuitBreaker{
+			policy: breakers.policy,
+			host:   host,
+		}
+		breakers.hosts[host] = breaker
+	}
+	return breaker
+}
+
+// circuitBreaker opens once FailureThreshold failures occur within Window,
+// rejects every attempt for OpenDuration and then lets HalfOpenProbes
+// attempts through, closing when all of them succeed and opening again on
+// the first failure
+type circuitBreaker struct {
+	policy    CircuitBreakerPolicy
+	host      string
+	mtx       sync.Mutex
+	state     circuitState
+	failures  []time.Time
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+// allow reserves an attempt, failing with ErrCircuitOpen when the circuit
+// does not let it through. Every allowed attempt has to be recorded, or
+// released when it never reached the host.
+func (breaker *circuitBreaker) allow(fields map[string]string) error {
+	if breaker == nil {
+		return nil
+	}
+	breaker.mtx.Lock()
+	defer breaker.mtx.Unlock()
+
+	if breaker.state == circuitOpen &&
+		time.Since(breaker.openedAt) >= breaker.policy.OpenDuration {
+		breaker.transition(circuitHalfOpen, fields)
+	}
+	fields["circuitState"] = breaker.state.String()
+	switch breaker.state {
+	case circuitOpen:
+		return fmt.Errorf("%w for host %s", ErrCircuitOpen, breaker.host)
+	case circuitHalfOpen:
+		if breaker.probes >= breaker.halfOpenProbes() {
+			return fmt.Errorf("%w for host %s", ErrCircuitOpen, breaker.host)
+		}
+		breaker.probes++
+	}
+	return nil
+}
+
+// record the outcome of an allowed attempt
+func (breaker *circuitBreaker) record(
+	failed bool,
+	fields map[string]string,
+) {
+	if breaker == nil {
+		return
+	}
+	breaker.mtx.Lock()
+	defer breaker.mtx.Unlock()
+
+	now := time.Now()
+	switch breaker.state {
+	case circuitHalfOpen:
+		if breaker.probes > 0 {
+			breaker.probes--
+		}
+		if failed {
+			breaker.open(now, fields)
+		} else {
+			breaker.successes++
+			if breaker.successes >= breaker.halfOpenProbes() {
+				breaker.transition(circuitClosed, fields)
+			}
+		}
+	case circuitClosed:
+		if !failed {
+			break
+		}
+		breaker.failures = append(breaker.failures, now)
+		breaker.trim(now)
+		if len(breaker.failures) >= breaker.policy.FailureThreshold {
+			breaker.open(now, fields)
+		}
+	}
+	fields["circuitState"] = breaker.state.String()
+}
+
+// release gives back the reservation of an allowed attempt that was never
+// sent, without counting it as a success or a failure
+func (breaker *circuitBreaker) release() {
+	if breaker == nil {
+		return
+	}
+	breaker.mtx.Lock()
+	defer breaker.mtx.Unlock()
+	if breaker.state == circuitHalfOpen && breaker.probes > 0 {
+		breaker.probes--
+	}
+}
+
+func (breaker *circuitBreaker) open(now time.Time, fields map[string]string) {
+	breaker.openedAt = now
+	breaker.transition(circuitOpen, fields)
+}
+
+func (breaker *circuitBreaker) transition(
+	state circuitState,
+	fields map[string]string,
+) {
+	transition := breaker.state.String() + "->" + state.String()
+	if prev, ok := fields["circuitTransition"]; ok {
+		transition = prev + "," + transition
+	}
+	fields["circuitTransition"] = transition
+	breaker.state = state
+	breaker.failures = breaker.failures[:0]
+	breaker.probes = 0
+	breaker.successes = 0
+}
+
+// trim drops the failures that fell out of the rolling window
+func (breaker *circuitBreaker) trim(now time.Time) {
+	if breaker.policy.Window <= 0 {
+		return
+	}
+	idx := 0
+	for idx < len(breaker.failures) &&
+		now.Sub(breaker.failures[idx]) > breaker.policy.Window {
+		idx++
+	}
+	breaker.failures = append(breaker.failures[:0], breaker.failures[idx:]...)
+}
+
+func (breaker *circuitBreaker) halfOpenProbes() int {
+	if breaker.policy.HalfOpenProbes < 1 {
+		return 1
+	}
+	return breaker.policy.HalfOpenProbes
+}
@@ -0,0 +1,92 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	tries := 0
+	status := 500
+	respond := func() (*http.Response, error) {
+		tries++
+		return &http.Response{
+			StatusCode: status,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: respond,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.Enabled = false
+	optn.CircuitBreaker = CircuitBreakerPolicy{
+		Enabled:          true,
+		FailureThreshold: 2,
+		Window:           time.Minute,
+		HalfOpenProbes:   1,
+		OpenDuration:     20 * time.Millisecond,
+	}
+	client = client.WithOptions(optn)
+
+	for idx := 0; idx < 2; idx++ {
+		if _, err := client.Get(context.TODO(), nil, "http://a.test", nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	_, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if tries != 2 {
+		t.Fatalf("open circuit sent the request, %d attempts", tries)
+	}
+	if _, err := client.Get(context.TODO(), nil, "http://b.test", nil); err != nil {
+		t.Fatalf("circuit of another host affected: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	status = 200
+	if _, err := client.Get(context.TODO(), nil, "http://a.test", nil); err != nil {
+		t.Fatalf("half open probe rejected: %v", err)
+	}
+	if _, err := client.Get(context.TODO(), nil, "http://a.test", nil); err != nil {
+		t.Fatalf("circuit did not close: %v", err)
+	}
+}
+
+func TestCircuitBreakerReleaseUnsentProbe(t *testing.T) {
+	breaker := &circuitBreaker{
+		policy: CircuitBreakerPolicy{
+			Enabled:          true,
+			FailureThreshold: 1,
+			HalfOpenProbes:   1,
+			OpenDuration:     time.Millisecond,
+		},
+		host: "a.test",
+	}
+	fields := map[string]string{}
+	breaker.record(true, fields)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := breaker.allow(fields); err != nil {
+		t.Fatalf("half open probe rejected: %v", err)
+	}
+	breaker.release()
+	if breaker.state != circuitHalfOpen {
+		t.Fatalf("unsent probe changed the circuit to %s", breaker.state)
+	}
+	if err := breaker.allow(fields); err != nil {
+		t.Fatalf("released probe was not given back: %v", err)
+	}
+}
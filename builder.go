@@ -0,0 +1,253 @@
+package gottp
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestBuilder fluent alternative to the positional request methods,
+// created through HttpClient.NewRequest
+type RequestBuilder struct {
+	client      *HttpClient
+	method      string
+	endpoint    string
+	pathParams  []string
+	query       url.Values
+	headers     map[string]string
+	payload     []byte
+	stream      *StreamBody
+	multipart   *MultipartForm
+	contentType string
+	timeout     *time.Duration
+	retry       *RetryPolicy
+	err         error
+}
+
+// NewRequest starts building a request, the endpoint may contain {}
+// placeholders filled by PathParams
+func (client *HttpClient) NewRequest(
+	method string,
+	endpoint string,
+) *RequestBuilder {
+	return &RequestBuilder{
+		client:   client,
+		method:   method,
+		endpoint: endpoint,
+		query:    url.Values{},
+		headers:  map[string]string{},
+	}
+}
+
+// PathParams values escaped into the endpoint's {} placeholders in order
+func (builder *RequestBuilder) PathParams(params ...string) *RequestBuilder {
+	builder.pathParams = append(builder.pathParams, params...)
+	return builder
+}
+
+// Query adds values for the query parameter
+func (builder *RequestBuilder) Query(
+	key string,
+	values ...string,
+) *RequestBuilder {
+	builder.query[key] = append(builder.query[key], values...)
+	return builder
+}
+
+// QueryValues adds every query parameter of the map
+func (builder *RequestBuilder) QueryValues(
+	qParam map[string][]string,
+) *RequestBuilder {
+	for key, values := range qParam {
+		builder.Query(key, values...)
+	}
+	return builder
+}
+
+// Header sets a header, on top of the client's default headers
+func (builder *RequestBuilder) Header(key string, value string) *RequestBuilder {
+	builder.headers[key] = value
+	return builder
+}
+
+// Headers sets every header of the map
+func (builder *RequestBuilder) Headers(
+	headers map[string]string,
+) *RequestBuilder {
+	for key, value := range headers {
+		builder.Header(key, value)
+	}
+	return builder
+}
+
+// JSON marshals the body as JSON, through MarshalJSON when it implements
+// IJsonDTO
+func (builder *RequestBuilder) JSON(body interface{}) *RequestBuilder {
+	byts, err := jsonDTO(body).MarshalJSON()
+	return builder.setPayload(byts, "application/json", err)
+}
+
+// XML marshals the body as XML
+func (builder *RequestBuilder) XML(body interface{}) *RequestBuilder {
+	byts, err := xml.Marshal(body)
+	return builder.setPayload(byts, "application/xml", err)
+}
+
+// Form encodes the body as a URL encoded form
+func (builder *RequestBuilder) Form(form url.Values) *RequestBuilder {
+	return builder.setPayload(
+		[]byte(form.Encode()),
+		"application/x-www-form-urlencoded",
+		nil,
+	)
+}
+
+// Multipart streams the form as multipart/form-data
+func (builder *RequestBuilder) Multipart(form *MultipartForm) *RequestBuilder {
+	builder.payload = nil
+	builder.stream = nil
+	builder.multipart = form
+	builder.contentType = ""
+	return builder
+}
+
+// Body streams the reader as is, see StreamBody for the length and retry
+// behaviour
+func (builder *RequestBuilder) Body(body io.Reader) *RequestBuilder {
+	return builder.Stream(StreamBody{Reader: body})
+}
+
+// Stream streams the body with its content type and length
+func (builder *RequestBuilder) Stream(body StreamBody) *RequestBuilder {
+	builder.payload = nil
+	builder.stream = &body
+	builder.multipart = nil
+	builder.contentType = body.ContentType
+	return builder
+}
+
+func (builder *RequestBuilder) setPayload(
+	byts []byte,
+	contentType string,
+	err error,
+) *RequestBuilder {
+	if err != nil {
+		builder.err = err
+		return builder
+	}
+	builder.payload = byts
+	builder.stream = nil
+	builder.multipart = nil
+	builder.contentType = contentType
+	return builder
+}
+
+// Timeout overrides ClientOptions.Timeout for this request
+func (builder *RequestBuilder) Timeout(timeout time.Duration) *RequestBuilder {
+	builder.timeout = &timeout
+	return builder
+}
+
+// Retry overrides ClientOptions.Retry for this request
+func (builder *RequestBuilder) Retry(policy RetryPolicy) *RequestBuilder {
+	builder.retry = &policy
+	return builder
+}
+
+// Do sends the request
+func (builder *RequestBuilder) Do(ctx context.Context) (*Response, error) {
+	req, done, err := builder.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	resp, err := builder.requestClient().runRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	respObj := Response(*resp)
+	return &respObj, nil
+}
+
+// build creates the request, the returned func closes the readers of a
+// streamed body once the call is done
+func (builder *RequestBuilder) build(
+	ctx context.Context,
+) (*http.Request, func(), error) {
+	done := func() {}
+	if builder.err != nil {
+		return nil, done, builder.err
+	}
+	endpoint, err := formatEp(
+		builder.endpoint,
+		builder.query,
+		builder.pathParams...,
+	)
+	if err != nil {
+		return nil, done, err
+	}
+
+	var req *http.Request
+	contentType := builder.contentType
+	if builder.payload != nil {
+		req, err = newBodyRequest(ctx, builder.method, endpoint, builder.payload)
+	} else if builder.multipart != nil {
+		boundary := multipartBoundary()
+		contentType = "multipart/form-data; boundary=" + boundary
+		req, err = http.NewRequestWithContext(ctx, builder.method, endpoint, nil)
+		if err == nil {
+			err = setStreamBody(
+				req,
+				builder.multipart.factory(boundary),
+				-1,
+				builder.multipart.replayable(),
+			)
+		}
+	} else if builder.stream != nil {
+		var factory bodyFactory
+		factory, done = builder.stream.factory()
+		req, err = http.NewRequestWithContext(ctx, builder.method, endpoint, nil)
+		if err == nil {
+			err = setStreamBody(
+				req,
+				factory,
+				builder.stream.length(),
+				builder.stream.replayable(),
+			)
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, builder.method, endpoint, nil)
+	}
+	if err != nil {
+		done()
+		return nil, func() {}, err
+	}
+
+	builder.client.formHeaders(req, builder.headers)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, done, nil
+}
+
+// requestClient the client with the request's overrides applied, sharing the
+// state of the resilience policies with the original client
+func (builder *RequestBuilder) requestClient() *HttpClient {
+	if builder.timeout == nil && builder.retry == nil {
+		return builder.client
+	}
+	clone := *builder.client
+	optn := *builder.client.optn
+	if builder.timeout != nil {
+		optn.Timeout = *builder.timeout
+	}
+	if builder.retry != nil {
+		optn.Retry = *builder.retry
+		clone.backoff = newBackoffStrategy(optn.Retry)
+	}
+	clone.optn = &optn
+	return &clone
+}
@@ -0,0 +1,98 @@
+package gottp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	var got *http.Request
+	var body string
+	capture := func(req *http.Request) (*http.Response, error) {
+		byts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		got, body = req, string(byts)
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: capture,
+		},
+		&MockTrace{},
+		map[string]string{"X-Default": "default"},
+		"",
+		"",
+		"",
+	)
+	res, err := client.NewRequest("PUT", "http://a.test/items/{}").
+		PathParams("42").
+		Query("page", "2").
+		Header("X-Custom", "custom").
+		XML(struct {
+			XMLName struct{} `xml:"item"`
+			Name    string   `xml:"name"`
+		}{Name: "new"}).
+		Timeout(time.Second).
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("invalid status code %d", res.StatusCode)
+	}
+	if got.URL.Path != "/items/42" || got.URL.Query().Get("page") != "2" {
+		t.Fatalf("unexpected url %s", got.URL)
+	}
+	if got.Header.Get("X-Default") != "default" ||
+		got.Header.Get("X-Custom") != "custom" ||
+		got.Header.Get("Content-Type") != "application/xml" {
+		t.Fatalf("unexpected headers %v", got.Header)
+	}
+	if body != "<item><name>new</name></item>" {
+		t.Fatalf("unexpected body %s", body)
+	}
+	if _, ok := got.Context().Deadline(); !ok {
+		t.Fatalf("request timeout not applied")
+	}
+}
+
+func TestRequestBuilderRetryOverride(t *testing.T) {
+	tries := 0
+	alwaysFail := func() (*http.Response, error) {
+		tries++
+		return &http.Response{
+			StatusCode: 503,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: alwaysFail,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	policy := DefaultOptions().Retry
+	policy.RetryCount = 2
+	policy.InitialBackoff = time.Millisecond
+	res, err := client.NewRequest("GET", "http://a.test").
+		Retry(policy).
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 503 || tries != 3 {
+		t.Fatalf("expected 3 attempts, got %d", tries)
+	}
+}
@@ -0,0 +1,169 @@
+package gottp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkheadUtilization snapshot of a bulkhead's usage
+type BulkheadUtilization struct {
+	InFlight int
+	Queued   int
+	Capacity int
+}
+
+// BulkheadStats utilization of the global bulkhead and of every host seen
+// so far
+type BulkheadStats struct {
+	Global BulkheadUtilization
+	Hosts  map[string]BulkheadUtilization
+}
+
+// bulkheads the concurrency limits of a client, globally and per host
+type bulkheads struct {
+	policy BulkheadPolicy
+	global *bulkhead
+	mtx    sync.Mutex
+	hosts  map[string]*bulkhead
+}
+
+func newBulkheads(policy BulkheadPolicy) *bulkheads {
+	if !policy.Enabled {
+		return nil
+	}
+	return &bulkheads{
+		policy: policy,
+		global: newBulkhead(policy.MaxConcurrentGlobal, policy.MaxQueue),
+		hosts:  map[string]*bulkhead{},
+	}
+}
+
+func (heads *bulkheads) host(host string) *bulkhead {
+	heads.mtx.Lock()
+	defer heads.mtx.Unlock()
+	head, ok := heads.hosts[host]
+	if !ok {
+		head = newBulkhead(heads.policy.MaxConcurrentPerHost, heads.policy.MaxQueue)
+		heads.hosts[host] = head
+	}
+	return head
+}
+
+// acquire a slot in the host's and then the global bulkhead, the returned
+// func releases both. The host slot comes first so requests queued behind a
+// saturated host do not hold global slots other hosts could use.
+func (heads *bulkheads) acquire(
+	ctx context.Context,
+	host string,
+) (func(), error) {
+	if heads == nil {
+		return func() {}, nil
+	}
+	head := heads.host(host)
+	if err := head.acquire(ctx, heads.policy.QueueTimeout); err != nil {
+		if err == ErrBulkheadFull {
+			err = fmt.Errorf("%w for host %s", err, host)
+		}
+		return nil, err
+	}
+	if err := heads.global.acquire(ctx, heads.policy.QueueTimeout); err != nil {
+		head.release()
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			head.release()
+			heads.global.release()
+		})
+	}, nil
+}
+
+func (heads *bulkheads) stats() BulkheadStats {
+	stats := BulkheadStats{
+		Hosts: map[string]BulkheadUtilization{},
+	}
+	if heads == nil {
+		return stats
+	}
+	stats.Global = heads.global.utilization()
+	heads.mtx.Lock()
+	defer heads.mtx.Unlock()
+	for host, head := range heads.hosts {
+		stats.Hosts[host] = head.utilization()
+	}
+	return stats
+}
+
+// bulkhead semaphore with a bounded wait queue, a nil bulkhead is unlimited
+type bulkhead struct {
+	slots    chan struct{}
+	maxQueue int64
+	queued   int64
+}
+
+func newBulkhead(capacity int, maxQueue int) *bulkhead {
+	if capacity <= 0 {
+		return nil
+	}
+	return &bulkhead{
+		slots:    make(chan struct{}, capacity),
+		maxQueue: int64(maxQueue),
+	}
+}
+
+func (head *bulkhead) acquire(
+	ctx context.Context,
+	timeout time.Duration,
+) error {
+	if head == nil {
+		return nil
+	}
+	select {
+	case head.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if atomic.AddInt64(&head.queued, 1) > head.maxQueue {
+		atomic.AddInt64(&head.queued, -1)
+		return ErrBulkheadFull
+	}
+	defer atomic.AddInt64(&head.queued, -1)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case head.slots <- struct{}{}:
+		return nil
+	case <-expired:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (head *bulkhead) release() {
+	if head == nil {
+		return
+	}
+	<-head.slots
+}
+
+func (head *bulkhead) utilization() BulkheadUtilization {
+	if head == nil {
+		return BulkheadUtilization{}
+	}
+	return BulkheadUtilization{
+		InFlight: len(head.slots),
+		Queued:   int(atomic.LoadInt64(&head.queued)),
+		Capacity: cap(head.slots),
+	}
+}
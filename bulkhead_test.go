@@ -0,0 +1,126 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBulkheadRejectsWhenFull(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := func() (*http.Response, error) {
+		entered <- struct{}{}
+		<-unblock
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: blocking,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Bulkhead = BulkheadPolicy{
+		Enabled:              true,
+		MaxConcurrentPerHost: 1,
+		MaxQueue:             1,
+		QueueTimeout:         10 * time.Millisecond,
+	}
+	client = client.WithOptions(optn)
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+		done <- err
+	}()
+	<-entered
+
+	stats := client.BulkheadStats().Hosts["a.test"]
+	if stats.InFlight != 1 || stats.Capacity != 1 {
+		t.Fatalf("unexpected utilization %+v", stats)
+	}
+
+	_, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected full bulkhead, got %v", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stats := client.BulkheadStats().Hosts["a.test"]; stats.InFlight != 0 {
+		t.Fatalf("slot not released %+v", stats)
+	}
+}
+
+func TestBulkheadSaturatedHostDoesNotBlockOthers(t *testing.T) {
+	entered := make(chan struct{}, 4)
+	unblock := make(chan struct{})
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Host == "slow.test" {
+					entered <- struct{}{}
+					<-unblock
+				}
+				return &http.Response{StatusCode: 200}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Bulkhead = BulkheadPolicy{
+		Enabled:              true,
+		MaxConcurrentPerHost: 1,
+		MaxConcurrentGlobal:  2,
+		MaxQueue:             4,
+	}
+	client = client.WithOptions(optn)
+
+	done := make(chan error, 3)
+	for idx := 0; idx < 3; idx++ {
+		go func() {
+			_, err := client.Get(context.TODO(), nil, "http://slow.test", nil)
+			done <- err
+		}()
+	}
+	<-entered
+	deadline := time.Now().Add(time.Second)
+	for client.BulkheadStats().Hosts["slow.test"].Queued != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("requests did not queue on the host %+v", client.BulkheadStats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	if _, err := client.Get(ctx, nil, "http://fast.test", nil); err != nil {
+		t.Fatalf("saturated host blocked another host: %v", err)
+	}
+	if global := client.BulkheadStats().Global; global.InFlight != 1 {
+		t.Fatalf("queued requests hold global slots %+v", global)
+	}
+
+	close(unblock)
+	for idx := 0; idx < 3; idx++ {
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+}
@@ -2,28 +2,24 @@
 package gottp
 
 import (
-	"bytes"
 	"context"
-	"encoding/xml"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/BetaLixT/go-resiliency/retrier"
-	hlpr "github.com/BetaLixT/gottp/helpers"
 )
 
 // HttpClient encapsulating REST functionality
 type HttpClient struct {
-	client  IInternalClient
-	tracer  ITracer
-	headers map[string]string
-	optn    *ClientOptions
-	retr    *retrier.Retrier
+	client      IInternalClient
+	tracer      ITracer
+	headers     map[string]string
+	optn        *ClientOptions
+	backoff     BackoffStrategy
+	breakers    *circuitBreakers
+	bulkheads   *bulkheads
+	limiters    *rateLimiters
+	latencies   *latencyTracker
+	middlewares []RoundTripMiddleware
 }
 
 // Get HTTP GET method
@@ -345,25 +341,124 @@ func (HttpClient *HttpClient) DeleteForm(
 	)
 }
 
+// PostMultipart HTTP POST method with a streamed multipart/form-data Body
+func (HttpClient *HttpClient) PostMultipart(
+	ctx context.Context,
+	headers map[string]string,
+	form *MultipartForm,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (*Response, error) {
+	return HttpClient.actionMultipart(
+		ctx,
+		"POST",
+		headers,
+		form,
+		endpoint,
+		qParam, params...,
+	)
+}
+
+// PutMultipart HTTP PUT method with a streamed multipart/form-data Body
+func (HttpClient *HttpClient) PutMultipart(
+	ctx context.Context,
+	headers map[string]string,
+	form *MultipartForm,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (*Response, error) {
+	return HttpClient.actionMultipart(
+		ctx,
+		"PUT",
+		headers,
+		form,
+		endpoint,
+		qParam, params...,
+	)
+}
+
+// PostStream HTTP POST method with a Body streamed from a reader
+func (HttpClient *HttpClient) PostStream(
+	ctx context.Context,
+	headers map[string]string,
+	body StreamBody,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (*Response, error) {
+	return HttpClient.actionStream(
+		ctx,
+		"POST",
+		headers,
+		body,
+		endpoint,
+		qParam, params...,
+	)
+}
+
+// PatchStream HTTP PATCH method with a Body streamed from a reader
+func (HttpClient *HttpClient) PatchStream(
+	ctx context.Context,
+	headers map[string]string,
+	body StreamBody,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (*Response, error) {
+	return HttpClient.actionStream(
+		ctx,
+		"PATCH",
+		headers,
+		body,
+		endpoint,
+		qParam, params...,
+	)
+}
+
+// PutStream HTTP PUT method with a Body streamed from a reader
+func (HttpClient *HttpClient) PutStream(
+	ctx context.Context,
+	headers map[string]string,
+	body StreamBody,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (*Response, error) {
+	return HttpClient.actionStream(
+		ctx,
+		"PUT",
+		headers,
+		body,
+		endpoint,
+		qParam, params...,
+	)
+}
+
 func (client *HttpClient) WithOptions(
 	optn *ClientOptions,
 ) *HttpClient {
 	return &HttpClient{
-		client:  client.client,
-		tracer:  client.tracer,
-		headers: client.headers,
-		optn:    optn,
-		retr: retrier.New(
-			retrier.ExponentialBackoff(
-				optn.Retry.RetryCount,
-				optn.Retry.InitialBackoff,
-			),
-			retrier.DefaultClassifier{},
-		),
+		client:      client.client,
+		tracer:      client.tracer,
+		headers:     client.headers,
+		optn:        optn,
+		backoff:     newBackoffStrategy(optn.Retry),
+		breakers:    newCircuitBreakers(optn.CircuitBreaker),
+		bulkheads:   newBulkheads(optn.Bulkhead),
+		limiters:    newRateLimiters(optn.RateLimit),
+		latencies:   newLatencyTracker(optn.Hedging),
+		middlewares: client.middlewares,
 	}
 }
 
-func (HttpClient *HttpClient) action(
+// BulkheadStats current utilization of the client's bulkheads, for metrics
+func (client *HttpClient) BulkheadStats() BulkheadStats {
+	return client.bulkheads.stats()
+}
+
+func (client *HttpClient) action(
 	ctx context.Context,
 	method string,
 	headers map[string]string,
@@ -371,23 +466,11 @@ func (HttpClient *HttpClient) action(
 	qParam map[string][]string,
 	pthParms ...string,
 ) (*Response, error) {
-	endpoint, err := formatEp(endpoint, qParam, pthParms...)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest(method, endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	HttpClient.formHeaders(req, headers)
-
-	resp, err := HttpClient.runRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	respObj := Response(*resp)
-	return &respObj, nil
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		Do(ctx)
 }
 
 func (client *HttpClient) actionBody(
@@ -399,33 +482,15 @@ func (client *HttpClient) actionBody(
 	qParam map[string][]string,
 	pthParms ...string,
 ) (*Response, error) {
-	endpoint, err := formatEp(endpoint, qParam, pthParms...)
-	if err != nil {
-		return nil, err
-	}
-
-	byts, err := body.MarshalJSON()
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(method, endpoint, bytes.NewReader(byts))
-	if err != nil {
-		return nil, err
-	}
-
-	client.formHeaders(req, headers)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.runRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	respObj := Response(*resp)
-	return &respObj, nil
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		JSON(body).
+		Do(ctx)
 }
 
-func (HttpClient *HttpClient) actionXML(
+func (client *HttpClient) actionXML(
 	ctx context.Context,
 	method string,
 	headers map[string]string,
@@ -434,33 +499,15 @@ func (HttpClient *HttpClient) actionXML(
 	qParam map[string][]string,
 	pthParms ...string,
 ) (*Response, error) {
-	endpoint, err := formatEp(endpoint, qParam, pthParms...)
-	if err != nil {
-		return nil, err
-	}
-
-	byts, err := xml.Marshal(body)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(method, endpoint, bytes.NewReader(byts))
-	if err != nil {
-		return nil, err
-	}
-
-	HttpClient.formHeaders(req, headers)
-	req.Header.Set("Content-Type", "application/xml")
-
-	resp, err := HttpClient.runRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	respObj := Response(*resp)
-	return &respObj, nil
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		XML(body).
+		Do(ctx)
 }
 
-func (HttpClient *HttpClient) actionForm(
+func (client *HttpClient) actionForm(
 	ctx context.Context,
 	method string,
 	headers map[string]string,
@@ -469,28 +516,46 @@ func (HttpClient *HttpClient) actionForm(
 	qParam map[string][]string,
 	pthParms ...string,
 ) (*Response, error) {
-	endpoint, err := formatEp(endpoint, qParam, pthParms...)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest(
-		method,
-		endpoint,
-		strings.NewReader(form.Encode()),
-	)
-	if err != nil {
-		return nil, err
-	}
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		Form(form).
+		Do(ctx)
+}
 
-	HttpClient.formHeaders(req, headers)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+func (client *HttpClient) actionMultipart(
+	ctx context.Context,
+	method string,
+	headers map[string]string,
+	form *MultipartForm,
+	endpoint string,
+	qParam map[string][]string,
+	pthParms ...string,
+) (*Response, error) {
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		Multipart(form).
+		Do(ctx)
+}
 
-	resp, err := HttpClient.runRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	respObj := Response(*resp)
-	return &respObj, nil
+func (client *HttpClient) actionStream(
+	ctx context.Context,
+	method string,
+	headers map[string]string,
+	body StreamBody,
+	endpoint string,
+	qParam map[string][]string,
+	pthParms ...string,
+) (*Response, error) {
+	return client.NewRequest(method, endpoint).
+		Headers(headers).
+		QueryValues(qParam).
+		PathParams(pthParms...).
+		Stream(body).
+		Do(ctx)
 }
 
 func (HttpClient *HttpClient) formHeaders(
@@ -509,77 +574,22 @@ func (client *HttpClient) runRequest(
 	ctx context.Context,
 	req *http.Request,
 ) (*http.Response, error) {
-	sid, err := hlpr.GenerateParentId()
-	ver, tid, _, rid, flg := client.tracer.ExtractTraceInfo(ctx)
-	if err == nil {
-		req.Header.Add(
-			"traceparent",
-			fmt.Sprintf("%s-%s-%s-%s", ver, tid, sid, flg),
-		)
-	} else {
-		req.Header.Add(
-			"traceparent",
-			fmt.Sprintf(
-				"%s-%s-%s-%s",
-				ver,
-				tid,
-				rid,
-				flg,
-			),
-		)
-	}
-	start := time.Now()
-	var resp *http.Response
-	if client.optn.Retry.Enabled {
-		client.retr.Run(func() error {
-			resp, err = client.client.Do(req)
-			if err == nil && resp.StatusCode > 299 {
-				for _, val := range client.optn.Retry.RetriableCodes {
-					if resp.StatusCode == val {
-						return errors.New("")
-					}
-				}
-			}
-			return nil
-		})
-	} else {
-		resp, err = client.client.Do(req)
+	call, ok := ctx.Value(callTraceKey{}).(*callTrace)
+	if !ok {
+		call = &callTrace{}
+		ctx = context.WithValue(ctx, callTraceKey{}, call)
 	}
-	end := time.Now()
-
+	req = req.WithContext(ctx)
+	resp, err := client.handler()(req)
 	if err != nil {
-		client.tracer.TraceDependency(
-			ctx,
-			sid,
-			"http",
-			req.URL.Hostname(),
-			fmt.Sprintf("%s %s", req.Method, req.URL.RequestURI()),
-			false,
-			start,
-			end,
-			// types.NewField("method", req.Method),
-			// types.NewField("error", err.Error()),
-			map[string]string{"method": req.Method, "error": err.Error()},
-		)
 		return nil, err
 	}
-	client.tracer.TraceDependency(
-		ctx,
-		sid,
-		"http",
-		req.URL.Hostname(),
-		fmt.Sprintf("%s %s", req.Method, req.URL.RequestURI()),
-		resp.StatusCode > 199 && resp.StatusCode < 300,
-		start,
-		end,
-		// types.NewField("method", req.Method),
-		// types.NewField("statusCode", strconv.Itoa(resp.StatusCode)),
-		map[string]string{
-			"method":     req.Method,
-			"statusCode": strconv.Itoa(resp.StatusCode),
-		},
-	)
-	return resp, err
+	if client.optn.ErrorOnStatus &&
+		(resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return nil, newHTTPError(req, resp, call.trace.TraceId)
+	}
+	resp.Body = limitBody(resp.Body, client.optn.MaxResponseBodySize)
+	return resp, nil
 }
 
 // TODO Pre calculating length and allocating might improve performance
@@ -645,17 +655,15 @@ func NewHttpClientProvider(
 ) *HttpClient {
 	optn := DefaultOptions()
 	return &HttpClient{
-		client:  http.DefaultClient,
-		tracer:  tracer,
-		headers: headers,
-		optn:    optn,
-		retr: retrier.New(
-			retrier.ExponentialBackoff(
-				optn.Retry.RetryCount,
-				optn.Retry.InitialBackoff,
-			),
-			retrier.DefaultClassifier{},
-		),
+		client:    http.DefaultClient,
+		tracer:    tracer,
+		headers:   headers,
+		optn:      optn,
+		backoff:   newBackoffStrategy(optn.Retry),
+		breakers:  newCircuitBreakers(optn.CircuitBreaker),
+		bulkheads: newBulkheads(optn.Bulkhead),
+		limiters:  newRateLimiters(optn.RateLimit),
+		latencies: newLatencyTracker(optn.Hedging),
 	}
 }
 
@@ -669,17 +677,15 @@ func NewHttpClientWithClientProvider(
 ) *HttpClient {
 	optn := DefaultOptions()
 	return &HttpClient{
-		client:  client,
-		tracer:  tracer,
-		headers: headers,
-		optn:    optn,
-		retr: retrier.New(
-			retrier.ExponentialBackoff(
-				optn.Retry.RetryCount,
-				optn.Retry.InitialBackoff,
-			),
-			retrier.DefaultClassifier{},
-		),
+		client:    client,
+		tracer:    tracer,
+		headers:   headers,
+		optn:      optn,
+		backoff:   newBackoffStrategy(optn.Retry),
+		breakers:  newCircuitBreakers(optn.CircuitBreaker),
+		bulkheads: newBulkheads(optn.Bulkhead),
+		limiters:  newRateLimiters(optn.RateLimit),
+		latencies: newLatencyTracker(optn.Hedging),
 	}
 }
 
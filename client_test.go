@@ -2,18 +2,26 @@ package gottp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 )
 
 type responder func() (*http.Response, error)
+type requestResponder func(*http.Request) (*http.Response, error)
 type MockClient struct {
-	resp responder
+	resp    responder
+	handler requestResponder
 }
 
-func (client *MockClient) Do(_ *http.Request) (*http.Response, error) {
+func (client *MockClient) Do(req *http.Request) (*http.Response, error) {
+	if client.handler != nil {
+		return client.handler(req)
+	}
 	return client.resp()
 }
 
@@ -121,3 +129,202 @@ func TestGetRetryFail(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestPostFormRetryReplaysBody(t *testing.T) {
+	tries := 0
+	bodies := []string{}
+	failTwice := func(req *http.Request) (*http.Response, error) {
+		byts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(byts))
+		if tries < 2 {
+			tries++
+			return &http.Response{
+				StatusCode: 500,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: failTwice,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	res, err := client.PostForm(
+		context.TODO(),
+		nil,
+		url.Values{"key": []string{"value"}},
+		"",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("invalid status code %d", res.StatusCode)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(bodies))
+	}
+	for idx, body := range bodies {
+		if body != "key=value" {
+			t.Errorf("attempt %d sent body %q", idx, body)
+		}
+	}
+}
+
+func TestGetAttemptTimeout(t *testing.T) {
+	hang := func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: hang,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Timeout = 10 * time.Millisecond
+	optn.Retry.RetryCount = 1
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	_, err := client.Get(context.TODO(), nil, "", nil)
+	if !errors.Is(err, ErrAttemptTimeout) {
+		t.Fatalf("expected attempt timeout, got %v", err)
+	}
+	if errors.Is(err, ErrCanceled) {
+		t.Fatalf("attempt timeout reported as cancellation")
+	}
+}
+
+func TestGetCanceledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	tries := 0
+	alwaysFail := func() (*http.Response, error) {
+		tries++
+		cancel()
+		return &http.Response{
+			StatusCode: 500,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: alwaysFail,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Minute
+	client = client.WithOptions(optn)
+
+	_, err := client.Get(ctx, nil, "", nil)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if tries != 1 {
+		t.Fatalf("expected a single attempt, got %d", tries)
+	}
+}
+
+func TestTransportErrorRetry(t *testing.T) {
+	tries := 0
+	failOnce := func() (*http.Response, error) {
+		tries++
+		if tries < 2 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	res, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 || tries != 2 {
+		t.Fatalf("expected retried success, got %d after %d", res.StatusCode, tries)
+	}
+
+	tries = 0
+	_, err = client.Post(context.TODO(), nil, "", nil)
+	if err == nil || tries != 1 {
+		t.Fatalf("expected non idempotent request to fail once, got %d", tries)
+	}
+}
+
+type statusOnlyClassifier struct{}
+
+func (statusOnlyClassifier) ShouldRetry(
+	attempt int,
+	req *http.Request,
+	resp *http.Response,
+	err error,
+) bool {
+	return err == nil && resp.StatusCode == 418 && attempt < 2
+}
+
+func TestCustomRetryClassifier(t *testing.T) {
+	tries := 0
+	teapot := func() (*http.Response, error) {
+		tries++
+		return &http.Response{
+			StatusCode: 418,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: teapot,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	optn.Retry.Classifier = statusOnlyClassifier{}
+	client = client.WithOptions(optn)
+
+	res, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 418 || tries != 2 {
+		t.Fatalf("expected 2 attempts, got %d", tries)
+	}
+}
@@ -0,0 +1,230 @@
+package gottp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GzipCodec gzip content coding, a zero Level uses gzip.DefaultCompression
+type GzipCodec struct {
+	Level int
+}
+
+func (GzipCodec) Encoding() string {
+	return "gzip"
+}
+
+func (codec GzipCodec) Encode(dst io.Writer) (io.WriteCloser, error) {
+	if codec.Level == 0 {
+		return gzip.NewWriter(dst), nil
+	}
+	return gzip.NewWriterLevel(dst, codec.Level)
+}
+
+func (GzipCodec) Decode(src io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(src)
+}
+
+// DeflateCodec deflate content coding, zlib wrapped as specified by HTTP,
+// decoding also accepts the raw deflate some servers send. A zero Level uses
+// zlib.DefaultCompression.
+type DeflateCodec struct {
+	Level int
+}
+
+func (DeflateCodec) Encoding() string {
+	return "deflate"
+}
+
+func (codec DeflateCodec) Encode(dst io.Writer) (io.WriteCloser, error) {
+	if codec.Level == 0 {
+		return zlib.NewWriter(dst), nil
+	}
+	return zlib.NewWriterLevel(dst, codec.Level)
+}
+
+func (DeflateCodec) Decode(src io.Reader) (io.ReadCloser, error) {
+	reader := bufio.NewReader(src)
+	header, err := reader.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(header) == 2 &&
+		header[0]&0x0f == 8 &&
+		(uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(reader)
+	}
+	return flate.NewReader(reader), nil
+}
+
+// codecs the codings known to the client by name, the policy's codecs take
+// precedence over the built in ones
+func (client *HttpClient) codecs() map[string]Codec {
+	codecs := map[string]Codec{
+		"gzip":    GzipCodec{},
+		"deflate": DeflateCodec{},
+	}
+	for _, codec := range client.optn.Compression.Codecs {
+		codecs[strings.ToLower(codec.Encoding())] = codec
+	}
+	return codecs
+}
+
+// acceptEncoding the Accept-Encoding advertising the policy's codecs ahead
+// of the built in ones
+func (client *HttpClient) acceptEncoding() string {
+	encodings := []string{}
+	seen := map[string]bool{}
+	for _, codec := range client.optn.Compression.Codecs {
+		encoding := strings.ToLower(codec.Encoding())
+		if !seen[encoding] {
+			seen[encoding] = true
+			encodings = append(encodings, encoding)
+		}
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if !seen[encoding] {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return strings.Join(encodings, ", ")
+}
+
+// compressionMiddleware compresses the request body and decodes the response
+// according to the compression policy
+func (client *HttpClient) compressionMiddleware(next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		if !client.optn.Compression.Enabled {
+			return next(req)
+		}
+		codecs := client.codecs()
+		if req.Header.Get("Accept-Encoding") == "" {
+			req.Header.Set("Accept-Encoding", client.acceptEncoding())
+		}
+		if err := client.compressBody(req, codecs); err != nil {
+			return nil, err
+		}
+		resp, err := next(req)
+		if err != nil {
+			return nil, err
+		}
+		decodeResponse(resp, codecs)
+		return resp, nil
+	}
+}
+
+// compressBody replaces an in memory body of at least MinSize bytes by its
+// compressed form, streamed bodies and bodies already encoded by the caller
+// are sent as they are
+func (client *HttpClient) compressBody(
+	req *http.Request,
+	codecs map[string]Codec,
+) error {
+	policy := client.optn.Compression
+	codec, ok := codecs[strings.ToLower(policy.RequestEncoding)]
+	if !ok ||
+		req.Body == nil ||
+		req.Body == http.NoBody ||
+		!canSendConcurrently(req) ||
+		req.ContentLength < int64(policy.MinSize) ||
+		req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	writer, err := codec.Encode(&buffer)
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(raw); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	byts := buffer.Bytes()
+	if err = setBody(req, bytesBody(byts), int64(len(byts))); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", codec.Encoding())
+	return nil
+}
+
+// decodeResponse decodes the response body when every coding listed in its
+// Content-Encoding is known, whether or not the client asked for it, and
+// leaves it untouched otherwise. Decoding is deferred to the first read so
+// empty and streamed bodies are not blocked on.
+func decodeResponse(resp *http.Response, codecs map[string]Codec) {
+	header := resp.Header.Get("Content-Encoding")
+	if header == "" || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	layers := []Codec{}
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+		codec, ok := codecs[encoding]
+		if !ok {
+			return
+		}
+		layers = append(layers, codec)
+	}
+
+	body := &decodedBody{raw: resp.Body}
+	reader := io.Reader(resp.Body)
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		reader = &lazyDecoder{src: reader, codec: layers[idx], body: body}
+	}
+	body.Reader = reader
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decodedBody response body read through its decoders, closing it closes
+// the decoders and the raw body
+type decodedBody struct {
+	io.Reader
+	raw      io.ReadCloser
+	decoders []io.Closer
+}
+
+func (body *decodedBody) Close() error {
+	for _, decoder := range body.decoders {
+		decoder.Close()
+	}
+	return body.raw.Close()
+}
+
+// lazyDecoder creates its decoder on the first read
+type lazyDecoder struct {
+	src     io.Reader
+	codec   Codec
+	body    *decodedBody
+	decoder io.ReadCloser
+}
+
+func (decoder *lazyDecoder) Read(p []byte) (int, error) {
+	if decoder.decoder == nil {
+		reader, err := decoder.codec.Decode(decoder.src)
+		if err != nil {
+			return 0, err
+		}
+		decoder.decoder = reader
+		decoder.body.decoders = append(decoder.body.decoders, reader)
+	}
+	return decoder.decoder.Read(p)
+}
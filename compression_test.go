@@ -0,0 +1,236 @@
+package gottp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func compressed(t *testing.T, encoding string, content string) []byte {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "zlib":
+		writer = zlib.NewWriter(&buffer)
+	default:
+		writer, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	}
+	writer.Write([]byte(content))
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func compressionClient(handler requestResponder) *HttpClient {
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: handler,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = 0
+	optn.Compression.Enabled = true
+	optn.Compression.MinSize = 8
+	return client.WithOptions(optn)
+}
+
+func TestCompressRequestBody(t *testing.T) {
+	bodies := []string{}
+	encodings := []string{}
+	failed := false
+	client := compressionClient(func(req *http.Request) (*http.Response, error) {
+		encodings = append(encodings, req.Header.Get("Content-Encoding"))
+		reader := io.Reader(req.Body)
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			reader = gz
+		}
+		byts, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(byts))
+		if !failed && req.Header.Get("Content-Encoding") == "gzip" {
+			failed = true
+			return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	})
+
+	payload := strings.Repeat("payload ", 16)
+	_, err := client.NewRequest("PUT", "").
+		Body(strings.NewReader(payload)).
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	_, err = client.NewRequest("PUT", "").
+		JSON(map[string]string{"a": strings.Repeat("b", 64)}).
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if encodings[0] != "" {
+		t.Fatalf("streamed body should not be compressed")
+	}
+	if len(bodies) != 3 || encodings[1] != "gzip" || encodings[2] != "gzip" ||
+		bodies[1] != bodies[2] || !strings.Contains(bodies[1], "bbbb") {
+		t.Fatalf("expected compressed body replayed, got %v %v", encodings, bodies)
+	}
+
+	encodings = encodings[:0]
+	_, err = client.NewRequest("PUT", "").JSON("tiny").Do(context.TODO())
+	if err != nil || encodings[0] != "" {
+		t.Fatalf("small body should not be compressed, got %v %v", encodings, err)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	cases := map[string][]byte{
+		"gzip":    compressed(t, "gzip", "hello gzip"),
+		"deflate": compressed(t, "zlib", "hello zlib"),
+		"DEFLATE": compressed(t, "flate", "hello flate"),
+	}
+	expected := map[string]string{
+		"gzip":    "hello gzip",
+		"deflate": "hello zlib",
+		"DEFLATE": "hello flate",
+	}
+	for encoding, body := range cases {
+		accept := ""
+		client := compressionClient(func(req *http.Request) (*http.Response, error) {
+			accept = req.Header.Get("Accept-Encoding")
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Encoding": {encoding}},
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		})
+		resp, err := client.Get(
+			context.TODO(),
+			map[string]string{"Accept-Encoding": "br"},
+			"",
+			nil,
+		)
+		if err != nil {
+			t.Fatalf("error encountered making request: %v", err)
+		}
+		byts, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(byts) != expected[encoding] {
+			t.Fatalf("unexpected %s body %q %v", encoding, byts, err)
+		}
+		if accept != "br" || resp.Header.Get("Content-Encoding") != "" {
+			t.Fatalf("unexpected headers %q %v", accept, resp.Header)
+		}
+	}
+
+	client := compressionClient(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept-Encoding") != "gzip, deflate" {
+			t.Errorf("unexpected Accept-Encoding %q", req.Header.Get("Accept-Encoding"))
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Encoding": {"br"}},
+			Body:       io.NopCloser(strings.NewReader("raw")),
+		}, nil
+	})
+	resp, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	byts, _ := io.ReadAll(resp.Body)
+	if string(byts) != "raw" || resp.Header.Get("Content-Encoding") != "br" {
+		t.Fatalf("unknown coding should be left untouched")
+	}
+}
+
+// reverseCodec stands in for a pluggable codec such as zstd
+type reverseCodec struct{}
+
+func (reverseCodec) Encoding() string {
+	return "x-reverse"
+}
+
+func (reverseCodec) Encode(dst io.Writer) (io.WriteCloser, error) {
+	return &reverseWriter{dst: dst}, nil
+}
+
+func (reverseCodec) Decode(src io.Reader) (io.ReadCloser, error) {
+	byts, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(reverse(byts))), nil
+}
+
+type reverseWriter struct {
+	dst    io.Writer
+	buffer bytes.Buffer
+}
+
+func (writer *reverseWriter) Write(p []byte) (int, error) {
+	return writer.buffer.Write(p)
+}
+
+func (writer *reverseWriter) Close() error {
+	_, err := writer.dst.Write(reverse(writer.buffer.Bytes()))
+	return err
+}
+
+func reverse(byts []byte) []byte {
+	reversed := make([]byte, len(byts))
+	for idx, b := range byts {
+		reversed[len(byts)-1-idx] = b
+	}
+	return reversed
+}
+
+func TestCustomCodec(t *testing.T) {
+	var sent []byte
+	client := compressionClient(func(req *http.Request) (*http.Response, error) {
+		sent, _ = io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: 200,
+			Header: http.Header{
+				"Content-Encoding": {"x-reverse, gzip"},
+			},
+			Body: io.NopCloser(bytes.NewReader(
+				compressed(t, "gzip", string(reverse([]byte("decoded")))),
+			)),
+		}, nil
+	})
+	optn := *client.optn
+	optn.Compression.RequestEncoding = "x-reverse"
+	optn.Compression.Codecs = []Codec{reverseCodec{}}
+	client = client.WithOptions(&optn)
+
+	resp, err := client.NewRequest("POST", "").
+		JSON("0123456789").
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if string(sent) != `"9876543210"` {
+		t.Fatalf("unexpected request body %q", sent)
+	}
+	byts, err := io.ReadAll(resp.Body)
+	if err != nil || string(byts) != "decoded" {
+		t.Fatalf("unexpected response body %q %v", byts, err)
+	}
+}
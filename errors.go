@@ -0,0 +1,59 @@
+package gottp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBodyNotReplayable returned when a request has to be retried but its body
+// can not be re-created
+var ErrBodyNotReplayable = errors.New(
+	"request body can not be replayed for retry",
+)
+
+var (
+	// ErrAttemptTimeout a single attempt exceeded ClientOptions.Timeout
+	ErrAttemptTimeout = errors.New("request attempt timed out")
+	// ErrDeadlineExceeded the request exceeded ClientOptions.OverallDeadline
+	ErrDeadlineExceeded = errors.New("request overall deadline exceeded")
+	// ErrCanceled the caller's context was canceled or expired
+	ErrCanceled = errors.New("request canceled")
+)
+
+// ContextError returned when a request is aborted by a context, Reason is one
+// of ErrAttemptTimeout, ErrDeadlineExceeded or ErrCanceled and can be matched
+// with errors.Is, as can the underlying context error
+type ContextError struct {
+	Reason   error
+	Attempts int
+	Err      error
+}
+
+func (err *ContextError) Error() string {
+	return fmt.Sprintf(
+		"%s after %d attempt(s): %v",
+		err.Reason,
+		err.Attempts,
+		err.Err,
+	)
+}
+
+func (err *ContextError) Unwrap() error {
+	return err.Err
+}
+
+func (err *ContextError) Is(target error) bool {
+	return target == err.Reason
+}
+
+// ErrCircuitOpen returned without sending the request while the circuit
+// breaker of the destination host is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrBulkheadFull returned without sending the request when the bulkhead has
+// no free slot and its wait queue is full or the queue timeout expired
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// ErrRateLimited returned without sending the request when the client side
+// rate limit is exhausted and the policy rejects instead of waiting
+var ErrRateLimited = errors.New("client rate limit exceeded")
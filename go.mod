@@ -1,5 +1,3 @@
 module github.com/BetaLixT/gottp
 
 go 1.18
-
-require github.com/BetaLixT/go-resiliency v1.2.0
@@ -0,0 +1,173 @@
+package gottpotel
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type spanIdKey struct{}
+
+type traceIdKey struct{}
+
+// rootTrace trace gottp started for a call made outside of any span, with
+// the sampling decision of ClientOptions.RootSampler
+type rootTrace struct {
+	id    trace.TraceID
+	flags trace.TraceFlags
+}
+
+// withRootTrace requests the trace id gottp generated for a call made
+// outside of any span, contexts with an active span keep their trace
+func withRootTrace(
+	ctx context.Context,
+	traceId string,
+	traceFlags string,
+) context.Context {
+	raw, err := hex.DecodeString(traceId)
+	if trace.SpanContextFromContext(ctx).IsValid() || err != nil || len(raw) != 16 {
+		return ctx
+	}
+	root := rootTrace{}
+	copy(root.id[:], raw)
+	if !root.id.IsValid() {
+		return ctx
+	}
+	if flags, err := hex.DecodeString(traceFlags); err == nil && len(flags) == 1 {
+		root.flags = trace.TraceFlags(flags[0])
+	}
+	return context.WithValue(ctx, traceIdKey{}, root)
+}
+
+// unsampledRoot reports whether the context belongs to a root trace gottp
+// decided not to sample
+func unsampledRoot(ctx context.Context) bool {
+	root, ok := ctx.Value(traceIdKey{}).(rootTrace)
+	return ok && !root.flags.IsSampled()
+}
+
+// withSpanId requests the span id for the span started from the context
+func withSpanId(ctx context.Context, spanId string) context.Context {
+	raw, err := hex.DecodeString(spanId)
+	if err != nil || len(raw) != 8 {
+		return ctx
+	}
+	var sid trace.SpanID
+	copy(sid[:], raw)
+	if !sid.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanIdKey{}, sid)
+}
+
+// withParentSpan makes the span of the id, within the trace of the context,
+// the parent of the span started from the context
+func withParentSpan(ctx context.Context, spanId string) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if root, ok := ctx.Value(traceIdKey{}).(rootTrace); ok {
+		sc = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    root.id,
+			TraceFlags: root.flags,
+		})
+	}
+	raw, err := hex.DecodeString(spanId)
+	if err != nil || len(raw) != 8 {
+		return ctx
+	}
+	var sid trace.SpanID
+	copy(sid[:], raw)
+	sc = sc.WithSpanID(sid)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, sc)
+}
+
+// IDGenerator sdk id generator reusing the span id gottp generated and
+// propagated for the dependency, deferring to Fallback for every other span
+type IDGenerator struct {
+	Fallback sdktrace.IDGenerator
+}
+
+// NewIDGenerator id generator falling back to the sdk's random generator,
+// install it with sdktrace.WithIDGenerator
+func NewIDGenerator() *IDGenerator {
+	return &IDGenerator{
+		Fallback: defaultIDGenerator(),
+	}
+}
+
+func (gen *IDGenerator) NewIDs(
+	ctx context.Context,
+) (trace.TraceID, trace.SpanID) {
+	tid, sid := gen.Fallback.NewIDs(ctx)
+	if root, ok := ctx.Value(traceIdKey{}).(rootTrace); ok {
+		tid = root.id
+	}
+	if requested, ok := ctx.Value(spanIdKey{}).(trace.SpanID); ok {
+		sid = requested
+	}
+	return tid, sid
+}
+
+func (gen *IDGenerator) NewSpanID(
+	ctx context.Context,
+	traceID trace.TraceID,
+) trace.SpanID {
+	if requested, ok := ctx.Value(spanIdKey{}).(trace.SpanID); ok {
+		return requested
+	}
+	return gen.Fallback.NewSpanID(ctx, traceID)
+}
+
+// randomIDGenerator random ids from the helpers' generators, drawing again
+// for the all zero ids the sdk treats as invalid and drawing from math/rand,
+// as the sdk's own generator does, when crypto/rand fails
+type randomIDGenerator struct{}
+
+func defaultIDGenerator() sdktrace.IDGenerator {
+	return randomIDGenerator{}
+}
+
+// the id sources, replaced by the tests
+var (
+	generateTraceId = hlpr.GenerateTraceIdRaw
+	generateSpanId  = hlpr.GenerateParentIdRaw
+)
+
+func (randomIDGenerator) NewIDs(
+	ctx context.Context,
+) (trace.TraceID, trace.SpanID) {
+	var tid trace.TraceID
+	for !tid.IsValid() {
+		raw, err := generateTraceId()
+		if err != nil {
+			binary.BigEndian.PutUint64(tid[:8], rand.Uint64())
+			binary.BigEndian.PutUint64(tid[8:], rand.Uint64())
+			continue
+		}
+		copy(tid[:], raw)
+	}
+	return tid, randomIDGenerator{}.NewSpanID(ctx, tid)
+}
+
+func (randomIDGenerator) NewSpanID(
+	ctx context.Context,
+	traceID trace.TraceID,
+) trace.SpanID {
+	var sid trace.SpanID
+	for !sid.IsValid() {
+		raw, err := generateSpanId()
+		if err != nil {
+			binary.BigEndian.PutUint64(sid[:], rand.Uint64())
+			continue
+		}
+		copy(sid[:], raw)
+	}
+	return sid
+}
@@ -0,0 +1,144 @@
+// Package gottpotel implements gottp's ITracer on top of an OpenTelemetry
+// TracerProvider, every dependency traced by the client becomes a client
+// span carrying the HTTP semantic convention attributes
+package gottpotel
+
+import (
+	"context"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/BetaLixT/gottp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/BetaLixT/gottp/gottpotel"
+
+// Tracer ITracer recording dependencies as OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ gottp.ITracer = (*Tracer)(nil)
+
+// NewTracer creates the tracer over the provider, install IDGenerator in
+// the provider so the span ids match the ones propagated downstream
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{
+		tracer: provider.Tracer(instrumentationName),
+	}
+}
+
+// ExtractTraceInfo the trace of the span active in the context, empty
+// values when there is none
+func (tracer *Tracer) ExtractTraceInfo(
+	ctx context.Context,
+) (ver, tid, pid, rid, flg string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", "", "", ""
+	}
+	return "00",
+		sc.TraceID().String(),
+		"",
+		sc.SpanID().String(),
+		hex.EncodeToString([]byte{byte(sc.TraceFlags())})
+}
+
+// TraceDependency records the dependency as a client span named after its
+// method, started at startTimestamp and ended at eventTimestamp, attempts
+// and hedges become children of the span named by their parentSpanId field.
+// Nothing is recorded for root traces the client's RootSampler did not
+// sample.
+func (tracer *Tracer) TraceDependency(
+	ctx context.Context,
+	spanId string,
+	dependencyType string,
+	serviceName string,
+	commandName string,
+	success bool,
+	startTimestamp time.Time,
+	eventTimestamp time.Time,
+	fields map[string]string,
+) {
+	attrs := []attribute.KeyValue{
+		attribute.String("server.address", serviceName),
+		attribute.String("gottp.dependency_type", dependencyType),
+		attribute.String("gottp.span_id", spanId),
+	}
+	if tid, ok := fields["traceId"]; ok {
+		ctx = withRootTrace(ctx, tid, fields["traceFlags"])
+		if unsampledRoot(ctx) {
+			return
+		}
+	}
+	statusCode := ""
+	errorMessage := ""
+	name := commandName
+	for key, value := range fields {
+		switch key {
+		case "method":
+			name = value
+			attrs = append(attrs, attribute.String("http.request.method", value))
+		case "url":
+			attrs = append(attrs, attribute.String("url.full", redactQuery(value)))
+		case "statusCode":
+			statusCode = value
+			if code, err := strconv.Atoi(value); err == nil {
+				attrs = append(
+					attrs,
+					attribute.Int("http.response.status_code", code),
+				)
+			}
+		case "error":
+			errorMessage = value
+		case "parentSpanId":
+			ctx = withParentSpan(ctx, value)
+		case "traceId", "traceFlags":
+		default:
+			attrs = append(attrs, attribute.String("gottp."+key, value))
+		}
+	}
+
+	_, span := tracer.tracer.Start(
+		withSpanId(ctx, spanId),
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(startTimestamp),
+		trace.WithAttributes(attrs...),
+	)
+	if !success {
+		errorType := statusCode
+		if errorType == "" {
+			errorType = "_OTHER"
+		}
+		span.SetAttributes(attribute.String("error.type", errorType))
+		span.SetStatus(codes.Error, errorMessage)
+	}
+	span.End(trace.WithTimestamp(eventTimestamp))
+}
+
+// redactQuery replaces the values of the URL's query parameters, which
+// commonly carry signatures and tokens, keeping their names
+func redactQuery(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	if parsed.RawQuery == "" {
+		return endpoint
+	}
+	query := parsed.Query()
+	for key, values := range query {
+		for idx := range values {
+			values[idx] = "REDACTED"
+		}
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,233 @@
+package gottpotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BetaLixT/gottp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type mockClient struct {
+	handler func(*http.Request) (*http.Response, error)
+}
+
+func (client *mockClient) Do(req *http.Request) (*http.Response, error) {
+	return client.handler(req)
+}
+
+func newTestTracer() (*Tracer, *tracetest.InMemoryExporter, trace.Tracer) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithIDGenerator(NewIDGenerator()),
+	)
+	return NewTracer(provider), exporter, provider.Tracer("test")
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestTraceDependency(t *testing.T) {
+	tracer, exporter, parentTracer := newTestTracer()
+	traceparent := ""
+	client := gottp.NewHttpClientWithClientProvider(
+		&mockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := gottp.DefaultOptions()
+	optn.Retry.Enabled = false
+	client = client.WithOptions(optn)
+
+	ctx, parent := parentTracer.Start(context.Background(), "parent")
+	_, err := client.Get(
+		ctx,
+		nil,
+		"http://orders.test/orders/1",
+		map[string][]string{"token": {"secret"}},
+	)
+	parent.End()
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	span, call := spans[0], spans[1]
+	if span.Name != "GET" || span.SpanKind != trace.SpanKindClient {
+		t.Fatalf("unexpected span %s %v", span.Name, span.SpanKind)
+	}
+	if call.Parent.SpanID() != parent.SpanContext().SpanID() ||
+		call.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("dependency span is not a child of the active span")
+	}
+	if span.Parent.SpanID() != call.SpanContext.SpanID() ||
+		attributes(span)["gottp.attempt"].AsString() != "1" {
+		t.Fatalf("attempt span is not a child of the dependency span")
+	}
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 ||
+		parts[1] != span.SpanContext.TraceID().String() ||
+		parts[2] != span.SpanContext.SpanID().String() ||
+		parts[3] != "01" {
+		t.Fatalf("traceparent %q does not match span %v", traceparent, span.SpanContext)
+	}
+
+	attrs := attributes(span)
+	if attrs["http.request.method"].AsString() != "GET" ||
+		attrs["server.address"].AsString() != "orders.test" ||
+		attrs["url.full"].AsString() != "http://orders.test/orders/1?token=REDACTED" ||
+		attrs["http.response.status_code"].AsInt64() != 503 ||
+		attrs["error.type"].AsString() != "503" {
+		t.Fatalf("unexpected attributes %v", span.Attributes)
+	}
+	if span.Status.Code != codes.Error {
+		t.Fatalf("expected error status, got %v", span.Status)
+	}
+}
+
+func TestTraceDependencyError(t *testing.T) {
+	tracer, exporter, _ := newTestTracer()
+	client := gottp.NewHttpClientWithClientProvider(
+		&mockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := gottp.DefaultOptions()
+	optn.Retry.Enabled = false
+	client = client.WithOptions(optn)
+
+	if _, err := client.Get(context.Background(), nil, "http://orders.test/", nil); err == nil {
+		t.Fatalf("expected transport error")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[1]
+	if span.Status.Code != codes.Error ||
+		!strings.Contains(span.Status.Description, "connection refused") ||
+		attributes(span)["error.type"].AsString() != "_OTHER" {
+		t.Fatalf("unexpected span status %v %v", span.Status, span.Attributes)
+	}
+}
+
+func TestTraceDependencyRoot(t *testing.T) {
+	tracer, exporter, _ := newTestTracer()
+	traceparent := ""
+	client := gottp.NewHttpClientWithClientProvider(
+		&mockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	if _, err := client.Get(context.Background(), nil, "http://orders.test/", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	attempt, call := spans[0], spans[1]
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 ||
+		call.Parent.IsValid() ||
+		call.SpanContext.TraceID().String() != parts[1] ||
+		attempt.SpanContext.TraceID().String() != parts[1] ||
+		attempt.SpanContext.SpanID().String() != parts[2] ||
+		attempt.Parent.SpanID() != call.SpanContext.SpanID() {
+		t.Fatalf("spans do not follow the generated root trace %q", traceparent)
+	}
+}
+
+func TestTraceDependencyUnsampledRoot(t *testing.T) {
+	tracer, exporter, _ := newTestTracer()
+	traceparent := ""
+	client := gottp.NewHttpClientWithClientProvider(
+		&mockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return &http.Response{StatusCode: 500, Body: http.NoBody}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := gottp.DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	optn.Retry.RetryCount = 1
+	optn.RootSampler = func(req *http.Request) bool {
+		return false
+	}
+	client = client.WithOptions(optn)
+	if _, err := client.Get(context.Background(), nil, "http://orders.test/", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+
+	if !strings.HasSuffix(traceparent, "-00") {
+		t.Fatalf("expected unsampled traceparent, got %q", traceparent)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("unsampled root trace exported %d spans", len(spans))
+	}
+}
+
+func TestRandomIDsWithoutCryptoRand(t *testing.T) {
+	traceIds, spanIds := generateTraceId, generateSpanId
+	defer func() {
+		generateTraceId, generateSpanId = traceIds, spanIds
+	}()
+	generateTraceId = func() ([]byte, error) {
+		return nil, errors.New("entropy unavailable")
+	}
+	generateSpanId = func() ([]byte, error) {
+		return nil, errors.New("entropy unavailable")
+	}
+
+	tid, sid := defaultIDGenerator().NewIDs(context.Background())
+	if !tid.IsValid() || !sid.IsValid() {
+		t.Fatalf("invalid ids %s %s", tid, sid)
+	}
+}
@@ -0,0 +1,267 @@
+package gottp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+// latencySamples number of recent latencies the hedging percentile is
+// computed from, and the minimum before it is used instead of the delay
+const (
+	latencySamples    = 128
+	minLatencySamples = 16
+)
+
+// latencyTracker ring buffer of recent attempt latencies
+type latencyTracker struct {
+	mtx     sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyTracker(policy HedgingPolicy) *latencyTracker {
+	if !policy.Enabled || policy.Percentile <= 0 {
+		return nil
+	}
+	return &latencyTracker{
+		samples: make([]time.Duration, 0, latencySamples),
+	}
+}
+
+func (tracker *latencyTracker) observe(latency time.Duration) {
+	if tracker == nil {
+		return
+	}
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	if len(tracker.samples) < latencySamples {
+		tracker.samples = append(tracker.samples, latency)
+		return
+	}
+	tracker.samples[tracker.next] = latency
+	tracker.next = (tracker.next + 1) % latencySamples
+}
+
+func (tracker *latencyTracker) percentile(
+	percentile float64,
+) (time.Duration, bool) {
+	if tracker == nil {
+		return 0, false
+	}
+	tracker.mtx.Lock()
+	if len(tracker.samples) < minLatencySamples {
+		tracker.mtx.Unlock()
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), tracker.samples...)
+	tracker.mtx.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(percentile * float64(len(sorted)-1))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx], true
+}
+
+// hedgeDelay the wait before firing the next hedge, the observed latency
+// percentile when enough samples exist and the configured delay otherwise
+func (client *HttpClient) hedgeDelay() time.Duration {
+	if delay, ok := client.latencies.percentile(
+		client.optn.Hedging.Percentile,
+	); ok {
+		return delay
+	}
+	return client.optn.Hedging.Delay
+}
+
+// transport sends a single attempt, hedging it when the policy applies to
+// the request
+func (client *HttpClient) transport(
+	req *http.Request,
+) (*http.Response, error) {
+	if !client.optn.Hedging.Enabled ||
+		client.optn.Hedging.MaxHedges < 1 ||
+		!isIdempotent(req) ||
+		!canSendConcurrently(req) {
+		start := time.Now()
+		resp, err := client.client.Do(req)
+		if err == nil {
+			client.latencies.observe(time.Since(start))
+		}
+		return resp, err
+	}
+	return client.hedge(req)
+}
+
+type hedgeResult struct {
+	idx   int
+	span  string
+	req   *http.Request
+	resp  *http.Response
+	err   error
+	start time.Time
+	end   time.Time
+}
+
+// hedge sends the attempt and fires up to MaxHedges additional copies while
+// none has answered, skipping the copies the rate limit has no token for,
+// the first successful response wins and the others are canceled and closed
+func (client *HttpClient) hedge(
+	req *http.Request,
+) (*http.Response, error) {
+	ctx := req.Context()
+	fields := traceFields(ctx)
+	maxHedges := client.optn.Hedging.MaxHedges
+	results := make(chan hedgeResult, maxHedges+1)
+	cancels := make([]context.CancelFunc, 0, maxHedges+1)
+
+	launch := func(idx int) error {
+		hctx, cancel := context.WithCancel(ctx)
+		var hreq *http.Request
+		if idx == 0 {
+			hreq = req.Clone(hctx)
+		} else {
+			var err error
+			hreq, err = newAttempt(hctx, req, idx)
+			if err != nil {
+				cancel()
+				return err
+			}
+			// every copy on the wire counts against the rate limit, a hedge
+			// never waits for a token
+			if err = client.limiters.take(hreq); err != nil {
+				cancel()
+				return err
+			}
+		}
+		cancels = append(cancels, cancel)
+		span := client.hedgeTraceparent(hreq)
+		go func() {
+			start := time.Now()
+			resp, err := client.client.Do(hreq)
+			results <- hedgeResult{
+				idx:   idx,
+				span:  span,
+				req:   hreq,
+				resp:  resp,
+				err:   err,
+				start: start,
+				end:   time.Now(),
+			}
+		}()
+		return nil
+	}
+
+	if err := launch(0); err != nil {
+		return nil, err
+	}
+	launched, pending := 1, 1
+	delay := client.hedgeDelay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var winner *hedgeResult
+	var last hedgeResult
+	for winner == nil && pending > 0 {
+		select {
+		case <-timer.C:
+			if launched > maxHedges {
+				continue
+			}
+			if err := launch(launched); err == nil {
+				launched++
+				pending++
+			}
+			timer.Reset(delay)
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				winner = &result
+			} else {
+				last = result
+			}
+			client.traceHedge(ctx, result, winner != nil)
+		}
+	}
+	fields["hedges"] = strconv.Itoa(launched)
+
+	if winner == nil {
+		return nil, last.err
+	}
+	client.latencies.observe(winner.end.Sub(winner.start))
+	for idx, cancel := range cancels {
+		if idx != winner.idx {
+			cancel()
+		}
+	}
+	if pending > 0 {
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				result := <-results
+				client.traceHedge(ctx, result, false)
+				discardResponse(result.resp)
+			}
+		}(pending)
+	}
+	return winner.resp, nil
+}
+
+// hedgeTraceparent gives the hedge its own span id, as a child of the
+// attempt's span, re-injecting the trace headers, returning the span id
+func (client *HttpClient) hedgeTraceparent(req *http.Request) string {
+	sid, err := hlpr.GenerateParentId()
+	if err != nil {
+		return ""
+	}
+	trace, ok := req.Context().Value(traceContextKey{}).(hlpr.TraceContext)
+	if ok {
+		trace.ParentId = trace.SpanId
+		trace.SpanId = sid
+		client.propagator().Inject(trace, req.Header)
+	}
+	return sid
+}
+
+func (client *HttpClient) traceHedge(
+	ctx context.Context,
+	result hedgeResult,
+	won bool,
+) {
+	fields := map[string]string{
+		"method": result.req.Method,
+		"url":    traceURL(result.req.URL),
+		"hedge":  strconv.Itoa(result.idx),
+		"winner": strconv.FormatBool(won),
+	}
+	trace, ok := result.req.Context().Value(traceContextKey{}).(hlpr.TraceContext)
+	if ok {
+		fields["parentSpanId"] = trace.SpanId
+	}
+	rootFields(result.req.Context(), fields)
+	success := false
+	if result.err != nil {
+		fields["error"] = result.err.Error()
+	} else {
+		fields["statusCode"] = strconv.Itoa(result.resp.StatusCode)
+		success = result.resp.StatusCode > 199 && result.resp.StatusCode < 300
+	}
+	client.tracer.TraceDependency(
+		ctx,
+		result.span,
+		"http",
+		result.req.URL.Hostname(),
+		fmt.Sprintf("%s %s", result.req.Method, result.req.URL.RequestURI()),
+		success,
+		result.start,
+		result.end,
+		fields,
+	)
+}
@@ -0,0 +1,146 @@
+package gottp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHedgingTakesFastestResponse(t *testing.T) {
+	var mtx sync.Mutex
+	calls := 0
+	canceled := make(chan struct{})
+	slowFirst := func(req *http.Request) (*http.Response, error) {
+		mtx.Lock()
+		calls++
+		call := calls
+		mtx.Unlock()
+		if call == 1 {
+			<-req.Context().Done()
+			close(canceled)
+			return nil, req.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: slowFirst,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Hedging = HedgingPolicy{
+		Enabled:   true,
+		MaxHedges: 1,
+		Delay:     10 * time.Millisecond,
+	}
+	client = client.WithOptions(optn)
+
+	res, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("invalid status code %d", res.StatusCode)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("losing hedge was not canceled")
+	}
+}
+
+func TestHedgingSkipsNonIdempotent(t *testing.T) {
+	calls := 0
+	slow := func() (*http.Response, error) {
+		calls++
+		time.Sleep(30 * time.Millisecond)
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: slow,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Hedging = HedgingPolicy{
+		Enabled:   true,
+		MaxHedges: 2,
+		Delay:     time.Millisecond,
+	}
+	client = client.WithOptions(optn)
+
+	if _, err := client.Post(context.TODO(), nil, "http://a.test", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("non idempotent request hedged, %d calls", calls)
+	}
+}
+
+func TestHedgingRespectsRateLimit(t *testing.T) {
+	var mtx sync.Mutex
+	calls := 0
+	slow := func() (*http.Response, error) {
+		mtx.Lock()
+		calls++
+		mtx.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: slow,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Hedging = HedgingPolicy{
+		Enabled:   true,
+		MaxHedges: 2,
+		Delay:     5 * time.Millisecond,
+	}
+	optn.RateLimit = RateLimitPolicy{
+		Enabled: true,
+		Rate:    0.001,
+		Burst:   1,
+	}
+	client = client.WithOptions(optn)
+
+	res, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("invalid status code %d", res.StatusCode)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if calls != 1 {
+		t.Fatalf("hedges sent without a token, %d requests", calls)
+	}
+}
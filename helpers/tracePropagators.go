@@ -0,0 +1,177 @@
+package hlpr
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// TraceContext trace of an outgoing dependency call, SpanId identifies the
+// call and ParentId the span it was made from, all values are hex encoded as
+// in the traceparent
+type TraceContext struct {
+	Version  string
+	TraceId  string
+	ParentId string
+	SpanId   string
+	Flags    string
+}
+
+// Sampled reports whether the sampled flag is set
+func (trace TraceContext) Sampled() bool {
+	flg, err := hexByte(trace.Flags)
+	return err == nil && flg&1 == 1
+}
+
+// Propagator writes the trace context into the headers of an outgoing
+// request in a given format
+type Propagator interface {
+	Inject(trace TraceContext, headers http.Header)
+	// Fields the header names written by Inject
+	Fields() []string
+}
+
+// W3CPropagator W3C traceparent header
+type W3CPropagator struct{}
+
+func (W3CPropagator) Inject(trace TraceContext, headers http.Header) {
+	headers.Set(
+		"traceparent",
+		fmt.Sprintf(
+			"%s-%s-%s-%s",
+			trace.Version,
+			trace.TraceId,
+			trace.SpanId,
+			trace.Flags,
+		),
+	)
+}
+
+func (W3CPropagator) Fields() []string {
+	return []string{"traceparent"}
+}
+
+// B3SinglePropagator zipkin b3 single header
+type B3SinglePropagator struct{}
+
+func (B3SinglePropagator) Inject(trace TraceContext, headers http.Header) {
+	value := fmt.Sprintf(
+		"%s-%s-%s",
+		trace.TraceId,
+		trace.SpanId,
+		sampledDigit(trace),
+	)
+	if trace.ParentId != "" {
+		value += "-" + trace.ParentId
+	}
+	headers.Set("b3", value)
+}
+
+func (B3SinglePropagator) Fields() []string {
+	return []string{"b3"}
+}
+
+// B3MultiPropagator zipkin X-B3-* headers
+type B3MultiPropagator struct{}
+
+func (B3MultiPropagator) Inject(trace TraceContext, headers http.Header) {
+	headers.Set("X-B3-TraceId", trace.TraceId)
+	headers.Set("X-B3-SpanId", trace.SpanId)
+	headers.Set("X-B3-Sampled", sampledDigit(trace))
+	if trace.ParentId != "" {
+		headers.Set("X-B3-ParentSpanId", trace.ParentId)
+	} else {
+		headers.Del("X-B3-ParentSpanId")
+	}
+}
+
+func (B3MultiPropagator) Fields() []string {
+	return []string{
+		"X-B3-TraceId",
+		"X-B3-SpanId",
+		"X-B3-ParentSpanId",
+		"X-B3-Sampled",
+	}
+}
+
+// JaegerPropagator jaeger uber-trace-id header, the deprecated parent span
+// id is always sent as 0
+type JaegerPropagator struct{}
+
+func (JaegerPropagator) Inject(trace TraceContext, headers http.Header) {
+	headers.Set(
+		"uber-trace-id",
+		fmt.Sprintf(
+			"%s:%s:0:%s",
+			trace.TraceId,
+			trace.SpanId,
+			sampledDigit(trace),
+		),
+	)
+}
+
+func (JaegerPropagator) Fields() []string {
+	return []string{"uber-trace-id"}
+}
+
+// XRayPropagator AWS X-Amzn-Trace-Id header, the first 8 hex digits of the
+// trace id form the epoch part of the X-Ray root
+type XRayPropagator struct{}
+
+func (XRayPropagator) Inject(trace TraceContext, headers http.Header) {
+	if len(trace.TraceId) != 32 {
+		return
+	}
+	headers.Set(
+		"X-Amzn-Trace-Id",
+		fmt.Sprintf(
+			"Root=1-%s-%s;Parent=%s;Sampled=%s",
+			trace.TraceId[:8],
+			trace.TraceId[8:],
+			trace.SpanId,
+			sampledDigit(trace),
+		),
+	)
+}
+
+func (XRayPropagator) Fields() []string {
+	return []string{"X-Amzn-Trace-Id"}
+}
+
+// CompositePropagator injects every format of the list
+type CompositePropagator []Propagator
+
+func (propagators CompositePropagator) Inject(
+	trace TraceContext,
+	headers http.Header,
+) {
+	for _, propagator := range propagators {
+		propagator.Inject(trace, headers)
+	}
+}
+
+func (propagators CompositePropagator) Fields() []string {
+	fields := []string{}
+	for _, propagator := range propagators {
+		fields = append(fields, propagator.Fields()...)
+	}
+	return fields
+}
+
+func sampledDigit(trace TraceContext) string {
+	if trace.Sampled() {
+		return "1"
+	}
+	return "0"
+}
+
+func hexByte(value string) (byte, error) {
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) != 1 {
+		return 0, fmt.Errorf(FLAG_INVALID_LENGTH)
+	}
+	return raw[0], nil
+}
@@ -0,0 +1,178 @@
+package hlpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TRACESTATE_MAX_MEMBERS = 32
+
+	TRACESTATE_TOO_MANY_MEMBERS = "tracestate has too many members"
+	TRACESTATE_INVALID_MEMBER   = "invalid tracestate member"
+	TRACESTATE_INVALID_KEY      = "invalid tracestate key"
+	TRACESTATE_INVALID_VALUE    = "invalid tracestate value"
+	TRACESTATE_DUPLICATE_KEY    = "duplicate tracestate key"
+)
+
+// TraceStateMember single key/value pair of a tracestate
+type TraceStateMember struct {
+	Key   string
+	Value string
+}
+
+// TraceState list of tracestate members, the most recently updated first
+type TraceState []TraceStateMember
+
+// ParseTracestate parses the tracestate header, passing every header line
+// when it was received more than once. Empty members are skipped, any
+// invalid member, duplicate key or more than 32 members fails the whole
+// tracestate as it must then not be propagated.
+func ParseTracestate(headers ...string) (TraceState, error) {
+	state := TraceState{}
+	seen := map[string]bool{}
+	for _, header := range headers {
+		for _, member := range strings.Split(header, ",") {
+			member = strings.Trim(member, " \t")
+			if member == "" {
+				continue
+			}
+			idx := strings.IndexByte(member, '=')
+			if idx < 0 {
+				return nil, fmt.Errorf(TRACESTATE_INVALID_MEMBER)
+			}
+			key, value := member[:idx], member[idx+1:]
+			if err := ValidateTracestateKey(key); err != nil {
+				return nil, err
+			}
+			if err := ValidateTracestateValue(value); err != nil {
+				return nil, err
+			}
+			if seen[key] {
+				return nil, fmt.Errorf(TRACESTATE_DUPLICATE_KEY)
+			}
+			seen[key] = true
+			state = append(state, TraceStateMember{Key: key, Value: value})
+			if len(state) > TRACESTATE_MAX_MEMBERS {
+				return nil, fmt.Errorf(TRACESTATE_TOO_MANY_MEMBERS)
+			}
+		}
+	}
+	return state, nil
+}
+
+// String serializes the tracestate for the header, empty when it has no
+// members
+func (state TraceState) String() string {
+	members := make([]string, 0, len(state))
+	for _, member := range state {
+		members = append(members, member.Key+"="+member.Value)
+	}
+	return strings.Join(members, ",")
+}
+
+// Get the value of the key
+func (state TraceState) Get(key string) (string, bool) {
+	for _, member := range state {
+		if member.Key == key {
+			return member.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns the tracestate with the key updated and moved to the front,
+// as required for a vendor mutating its entry, dropping the rightmost
+// members once it grows beyond 32
+func (state TraceState) Set(key string, value string) (TraceState, error) {
+	if err := ValidateTracestateKey(key); err != nil {
+		return nil, err
+	}
+	if err := ValidateTracestateValue(value); err != nil {
+		return nil, err
+	}
+	updated := make(TraceState, 0, len(state)+1)
+	updated = append(updated, TraceStateMember{Key: key, Value: value})
+	for _, member := range state {
+		if member.Key != key {
+			updated = append(updated, member)
+		}
+	}
+	if len(updated) > TRACESTATE_MAX_MEMBERS {
+		updated = updated[:TRACESTATE_MAX_MEMBERS]
+	}
+	return updated, nil
+}
+
+// Delete returns the tracestate without the key
+func (state TraceState) Delete(key string) TraceState {
+	updated := make(TraceState, 0, len(state))
+	for _, member := range state {
+		if member.Key != key {
+			updated = append(updated, member)
+		}
+	}
+	return updated
+}
+
+// ValidateTracestateKey checks the key against the simple-key and
+// multi-tenant-key (tenant@system) grammars
+func ValidateTracestateKey(key string) error {
+	tenant, system := key, ""
+	multiTenant := false
+	if idx := strings.IndexByte(key, '@'); idx >= 0 {
+		tenant, system = key[:idx], key[idx+1:]
+		multiTenant = true
+	}
+	if multiTenant {
+		if len(tenant) < 1 || len(tenant) > 241 ||
+			!isLcAlpha(tenant[0]) && !isDigit(tenant[0]) ||
+			!isKeyChars(tenant[1:]) ||
+			len(system) < 1 || len(system) > 14 ||
+			!isLcAlpha(system[0]) ||
+			!isKeyChars(system[1:]) {
+			return fmt.Errorf(TRACESTATE_INVALID_KEY)
+		}
+		return nil
+	}
+	if len(key) < 1 || len(key) > 256 ||
+		!isLcAlpha(key[0]) ||
+		!isKeyChars(key[1:]) {
+		return fmt.Errorf(TRACESTATE_INVALID_KEY)
+	}
+	return nil
+}
+
+// ValidateTracestateValue checks the value is at most 256 printable ascii
+// characters other than comma and equals, not ending with a space
+func ValidateTracestateValue(value string) error {
+	if len(value) < 1 || len(value) > 256 || value[len(value)-1] == ' ' {
+		return fmt.Errorf(TRACESTATE_INVALID_VALUE)
+	}
+	for idx := 0; idx < len(value); idx++ {
+		chr := value[idx]
+		if chr < 0x20 || chr > 0x7e || chr == ',' || chr == '=' {
+			return fmt.Errorf(TRACESTATE_INVALID_VALUE)
+		}
+	}
+	return nil
+}
+
+func isLcAlpha(chr byte) bool {
+	return chr >= 'a' && chr <= 'z'
+}
+
+func isDigit(chr byte) bool {
+	return chr >= '0' && chr <= '9'
+}
+
+func isKeyChars(chars string) bool {
+	for idx := 0; idx < len(chars); idx++ {
+		chr := chars[idx]
+		if !isLcAlpha(chr) && !isDigit(chr) &&
+			chr != '_' && chr != '-' && chr != '*' && chr != '/' {
+			return false
+		}
+	}
+	return true
+}
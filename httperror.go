@@ -0,0 +1,138 @@
+package gottp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxErrorSnippet bytes of the response body kept by an HTTPError
+const maxErrorSnippet = 4 << 10
+
+// HTTPError returned for non 2xx responses when ClientOptions.ErrorOnStatus
+// is set
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Header     http.Header
+	// Body the beginning of the response body, at most 4KiB
+	Body    []byte
+	TraceId string
+	// Problem the decoded body of application/problem+json responses
+	Problem *ProblemDetails
+}
+
+func (err *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", err.Method, err.URL, err.Status)
+	if err.Problem != nil {
+		msg += ": " + err.Problem.Error()
+	} else if len(err.Body) > 0 {
+		msg += ": " + string(err.Body)
+	}
+	return msg
+}
+
+func (err *HTTPError) Unwrap() error {
+	if err.Problem == nil {
+		return nil
+	}
+	return err.Problem
+}
+
+// newHTTPError reads the body snippet and closes the response, tid is the
+// trace the call was made in whichever format it was propagated with
+func newHTTPError(
+	req *http.Request,
+	resp *http.Response,
+	tid string,
+) *HTTPError {
+	var body []byte
+	if resp.Body != nil {
+		limit := int64(maxErrorSnippet)
+		if isProblem(resp.Header) {
+			limit = maxProblemSize
+		}
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, limit))
+		discardResponse(resp)
+	}
+	snippet := body
+	if len(snippet) > maxErrorSnippet {
+		snippet = snippet[:maxErrorSnippet]
+	}
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     status,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Header:     resp.Header,
+		Body:       snippet,
+		TraceId:    tid,
+		Problem:    parseProblem(resp.Header, body),
+	}
+}
+
+// HTTPStatus the status code carried by the error, if it is an HTTPError
+func HTTPStatus(err error) (int, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode, true
+	}
+	return 0, false
+}
+
+// IsNotFound the error is an HTTPError with status 404
+func IsNotFound(err error) bool {
+	code, ok := HTTPStatus(err)
+	return ok && code == http.StatusNotFound
+}
+
+// IsUnauthorized the error is an HTTPError with status 401
+func IsUnauthorized(err error) bool {
+	code, ok := HTTPStatus(err)
+	return ok && code == http.StatusUnauthorized
+}
+
+// IsForbidden the error is an HTTPError with status 403
+func IsForbidden(err error) bool {
+	code, ok := HTTPStatus(err)
+	return ok && code == http.StatusForbidden
+}
+
+// IsConflict the error is an HTTPError with status 409
+func IsConflict(err error) bool {
+	code, ok := HTTPStatus(err)
+	return ok && code == http.StatusConflict
+}
+
+// IsRetriable the request failed for a transient reason and may succeed if
+// sent again later, a timed out attempt, a client side limit or an
+// HTTPError with status 408, 425, 429 or a 5xx other than 501 and 505
+func IsRetriable(err error) bool {
+	if errors.Is(err, ErrAttemptTimeout) ||
+		errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, ErrBulkheadFull) ||
+		errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	code, ok := HTTPStatus(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooEarly,
+		http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented,
+		http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code > 499
+}
@@ -0,0 +1,112 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+type staticTrace struct {
+	MockTrace
+}
+
+func (m *staticTrace) ExtractTraceInfo(
+	ctx context.Context,
+) (ver, tid, pid, rid, flg string) {
+	return "00",
+		"0af7651916cd43dd8448eb211c80319c",
+		"b7ad6b7169203331",
+		"b7ad6b7169203331",
+		"01"
+}
+
+func TestErrorOnStatus(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader("no such item")}
+	notFound := func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 404,
+			Header:     http.Header{"X-Request-Id": []string{"abc"}},
+			Body:       body,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: notFound,
+		},
+		&staticTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.ErrorOnStatus = true
+	client = client.WithOptions(optn)
+
+	_, err := client.Get(context.TODO(), nil, "http://a.test/items/{}", nil, "1")
+	if !IsNotFound(err) || IsRetriable(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	var httpErr *HTTPError
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &httpErr) {
+		t.Fatalf("error is not an HTTPError")
+	}
+	if httpErr.Method != "GET" ||
+		!strings.HasPrefix(httpErr.URL, "http://a.test/items/1") ||
+		string(httpErr.Body) != "no such item" ||
+		httpErr.Header.Get("X-Request-Id") != "abc" ||
+		httpErr.TraceId != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("unexpected error contents %+v", httpErr)
+	}
+	if !body.closed {
+		t.Fatalf("response body not closed")
+	}
+}
+
+func TestIsRetriable(t *testing.T) {
+	if !IsRetriable(&HTTPError{StatusCode: 503}) {
+		t.Errorf("503 not retriable")
+	}
+	if IsRetriable(&HTTPError{StatusCode: 501}) {
+		t.Errorf("501 retriable")
+	}
+	if !IsRetriable(&ContextError{Reason: ErrAttemptTimeout, Err: context.DeadlineExceeded}) {
+		t.Errorf("attempt timeout not retriable")
+	}
+	if IsRetriable(&ContextError{Reason: ErrCanceled, Err: context.Canceled}) {
+		t.Errorf("cancellation retriable")
+	}
+}
+
+func TestHTTPErrorTraceIdWithoutTraceparent(t *testing.T) {
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: func() (*http.Response, error) {
+				return &http.Response{StatusCode: 500, Body: http.NoBody}, nil
+			},
+		},
+		&staticTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.Enabled = false
+	optn.ErrorOnStatus = true
+	optn.Propagator = hlpr.B3MultiPropagator{}
+	client = client.WithOptions(optn)
+
+	_, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) ||
+		httpErr.TraceId != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("trace id missing with the b3 propagator, got %v", err)
+	}
+}
@@ -2,12 +2,13 @@ package gottp
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"time"
 )
 
 type IInternalClient interface {
-	Do (*http.Request) (*http.Response, error)
+	Do(*http.Request) (*http.Response, error)
 }
 
 type ITracer interface {
@@ -27,6 +28,40 @@ type ITracer interface {
 	)
 }
 
+// ITraceStateProvider optionally implemented by an ITracer to propagate the
+// W3C tracestate of the context alongside the traceparent, as the raw header
+// value
+type ITraceStateProvider interface {
+	ExtractTraceState(ctx context.Context) string
+}
+
+// RetryClassifier decides whether a finished attempt should be retried,
+// attempt counts the attempts made so far starting at 1 and resp is nil when
+// the attempt failed with err
+type RetryClassifier interface {
+	ShouldRetry(
+		attempt int,
+		req *http.Request,
+		resp *http.Response,
+		err error,
+	) bool
+}
+
+// BackoffStrategy computes the wait before the next attempt, attempt counts
+// the attempts made so far starting at 1 and previous is the last wait, zero
+// before the first retry
+type BackoffStrategy interface {
+	Backoff(attempt int, previous time.Duration) time.Duration
+}
+
+// Codec content coding, as named in Content-Encoding, used to compress
+// request bodies and decode responses
+type Codec interface {
+	Encoding() string
+	Encode(dst io.Writer) (io.WriteCloser, error)
+	Decode(src io.Reader) (io.ReadCloser, error)
+}
+
 type IJsonDTO interface {
 	MarshalJSON() ([]byte, error)
 	UnmarshalJSON([]byte) error
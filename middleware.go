@@ -0,0 +1,338 @@
+package gottp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+// Handler sends a request, bound to its context, and returns the response
+type Handler func(req *http.Request) (*http.Response, error)
+
+// RoundTripMiddleware wraps a handler with cross cutting behaviour such as
+// signing, logging or metrics
+type RoundTripMiddleware func(next Handler) Handler
+
+// Use returns a copy of the client with the middlewares appended to its
+// call middlewares, see chain for where they run
+func (client *HttpClient) Use(middlewares ...RoundTripMiddleware) *HttpClient {
+	clone := *client
+	clone.middlewares = append(
+		append([]RoundTripMiddleware{}, client.middlewares...),
+		middlewares...,
+	)
+	return &clone
+}
+
+// chain the middlewares ordered from the outermost to the innermost:
+//
+//  1. the built in tracing, injecting the traceparent header and tracing
+//     the dependency once per call
+//  2. the built in compression, compressing the request body and decoding
+//     the response once per call
+//  3. ClientOptions.Middlewares followed by the middlewares added with Use,
+//     invoked once per call
+//  4. the built in retry, running the resilience policies and invoking the
+//     rest of the chain once per attempt
+//  5. ClientOptions.AttemptMiddlewares, invoked once per attempt
+//
+// the chain ends in the transport which hedges the attempt when configured
+func (client *HttpClient) chain() []RoundTripMiddleware {
+	chain := make(
+		[]RoundTripMiddleware,
+		0,
+		len(client.optn.Middlewares)+
+			len(client.middlewares)+
+			len(client.optn.AttemptMiddlewares)+
+			3,
+	)
+	chain = append(chain, client.tracingMiddleware)
+	chain = append(chain, client.compressionMiddleware)
+	chain = append(chain, client.optn.Middlewares...)
+	chain = append(chain, client.middlewares...)
+	chain = append(chain, client.retryMiddleware)
+	chain = append(chain, client.optn.AttemptMiddlewares...)
+	return chain
+}
+
+// handler the transport wrapped by the whole chain
+func (client *HttpClient) handler() Handler {
+	handler := Handler(client.transport)
+	chain := client.chain()
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		handler = chain[idx](handler)
+	}
+	return handler
+}
+
+type traceFieldsKey struct{}
+
+// traceFields the fields of the dependency being traced for the request,
+// middlewares further down the chain annotate it
+func traceFields(ctx context.Context) map[string]string {
+	if fields, ok := ctx.Value(traceFieldsKey{}).(map[string]string); ok {
+		return fields
+	}
+	return map[string]string{}
+}
+
+type traceContextKey struct{}
+
+// callTraceKey holds a *callTrace the tracing middleware fills in, for the
+// callers outside of the chain
+type callTraceKey struct{}
+
+// callTrace the trace of a call and the dependency it was traced as
+type callTrace struct {
+	trace hlpr.TraceContext
+	root  bool
+	host  string
+	uri   string
+	url   string
+}
+
+// propagator the configured propagation format, W3C traceparent by default
+func (client *HttpClient) propagator() hlpr.Propagator {
+	if client.optn.Propagator != nil {
+		return client.optn.Propagator
+	}
+	return hlpr.W3CPropagator{}
+}
+
+// tracingMiddleware injects the trace headers through the propagator along
+// with the tracestate and traces the call as a dependency
+func (client *HttpClient) tracingMiddleware(next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		sid, err := hlpr.GenerateParentId()
+		ver, tid, _, rid, flg := client.tracer.ExtractTraceInfo(ctx)
+		if err != nil {
+			sid = rid
+		}
+		trace := hlpr.TraceContext{
+			Version:  ver,
+			TraceId:  tid,
+			ParentId: rid,
+			SpanId:   sid,
+			Flags:    flg,
+		}
+		root := false
+		if !validTraceInfo(ver, tid, rid, flg) {
+			if rootTrace, err := client.rootTrace(req); err == nil {
+				trace, root = rootTrace, true
+			}
+		}
+		client.propagator().Inject(trace, req.Header)
+		client.injectTracestate(ctx, req)
+		if call, ok := ctx.Value(callTraceKey{}).(*callTrace); ok {
+			*call = callTrace{
+				trace: trace,
+				root:  root,
+				host:  req.URL.Hostname(),
+				uri:   req.URL.RequestURI(),
+				url:   traceURL(req.URL),
+			}
+		}
+		fields := map[string]string{
+			"method": req.Method,
+			"url":    traceURL(req.URL),
+		}
+		start := time.Now()
+		tctx := context.WithValue(ctx, traceFieldsKey{}, fields)
+		tctx = context.WithValue(tctx, traceContextKey{}, trace)
+		if root {
+			tctx = context.WithValue(tctx, rootTraceKey{}, trace)
+			rootFields(tctx, fields)
+		}
+		resp, err := next(req.WithContext(tctx))
+		end := time.Now()
+
+		if err != nil {
+			fields["error"] = err.Error()
+			client.tracer.TraceDependency(
+				ctx,
+				trace.SpanId,
+				"http",
+				req.URL.Hostname(),
+				fmt.Sprintf("%s %s", req.Method, req.URL.RequestURI()),
+				false,
+				start,
+				end,
+				fields,
+			)
+			return nil, err
+		}
+		fields["statusCode"] = strconv.Itoa(resp.StatusCode)
+		client.tracer.TraceDependency(
+			ctx,
+			trace.SpanId,
+			"http",
+			req.URL.Hostname(),
+			fmt.Sprintf("%s %s", req.Method, req.URL.RequestURI()),
+			resp.StatusCode > 199 && resp.StatusCode < 300,
+			start,
+			end,
+			fields,
+		)
+		return resp, err
+	}
+}
+
+type rootTraceKey struct{}
+
+// traceURL the URL reported to the tracer, without the credentials it may
+// carry
+func traceURL(endpoint *url.URL) string {
+	redacted := *endpoint
+	redacted.User = nil
+	return redacted.String()
+}
+
+// validTraceInfo reports whether the values extracted by the tracer form a
+// valid traceparent with non zero trace and span ids
+func validTraceInfo(ver, tid, rid, flg string) bool {
+	traceparent := fmt.Sprintf("%s-%s-%s-%s", ver, tid, rid, flg)
+	if _, _, _, _, err := hlpr.ParseTraceparent(traceparent); err != nil {
+		return false
+	}
+	vers, rawTid, rawRid, _, err := hlpr.ParseTraceparentRaw(traceparent)
+	if err != nil || vers[0] == 0xff {
+		return false
+	}
+	return hlpr.ValidateTraceIdValue(rawTid) == nil &&
+		hlpr.ValidateParentIdValue(rawRid) == nil
+}
+
+// rootTrace starts a new trace for a call made outside of any trace, the
+// call's span being its root
+func (client *HttpClient) rootTrace(
+	req *http.Request,
+) (hlpr.TraceContext, error) {
+	sampled := client.optn.RootSampler == nil || client.optn.RootSampler(req)
+	traceparent, err := hlpr.GenerateNewTraceparent(sampled)
+	if err != nil {
+		return hlpr.TraceContext{}, err
+	}
+	ver, tid, sid, flg, err := hlpr.ParseTraceparent(traceparent)
+	if err != nil {
+		return hlpr.TraceContext{}, err
+	}
+	return hlpr.TraceContext{
+		Version: ver,
+		TraceId: tid,
+		SpanId:  sid,
+		Flags:   flg,
+	}, nil
+}
+
+// rootFields reports the trace generated for the call and its sampling
+// decision, tracers have no other way to learn about a trace started by the
+// client
+func rootFields(ctx context.Context, fields map[string]string) {
+	if trace, ok := ctx.Value(rootTraceKey{}).(hlpr.TraceContext); ok {
+		setRootFields(trace, fields)
+	}
+}
+
+func setRootFields(trace hlpr.TraceContext, fields map[string]string) {
+	fields["traceId"] = trace.TraceId
+	fields["traceFlags"] = trace.Flags
+	fields["rootTrace"] = "true"
+}
+
+// injectTracestate propagates the tracestate when the tracer provides one, a
+// malformed tracestate is dropped rather than forwarded
+func (client *HttpClient) injectTracestate(
+	ctx context.Context,
+	req *http.Request,
+) {
+	provider, ok := client.tracer.(ITraceStateProvider)
+	if !ok {
+		return
+	}
+	state, err := hlpr.ParseTracestate(provider.ExtractTraceState(ctx))
+	if err != nil || len(state) == 0 {
+		req.Header.Del("tracestate")
+		return
+	}
+	req.Header.Set("tracestate", state.String())
+}
+
+// attemptTrace gives the attempt its own span id, as a child of the call's
+// span, and injects it into the attempt's trace headers
+func (client *HttpClient) attemptTrace(req *http.Request) *http.Request {
+	trace, ok := req.Context().Value(traceContextKey{}).(hlpr.TraceContext)
+	if !ok {
+		return req
+	}
+	sid, err := hlpr.GenerateParentId()
+	if err != nil {
+		return req
+	}
+	trace.ParentId = trace.SpanId
+	trace.SpanId = sid
+	client.propagator().Inject(trace, req.Header)
+	return req.WithContext(
+		context.WithValue(req.Context(), traceContextKey{}, trace),
+	)
+}
+
+// traceAttempt traces a single attempt with its number, the backoff waited
+// before it and the reason the previous attempt was retried
+func (client *HttpClient) traceAttempt(
+	req *http.Request,
+	start time.Time,
+	attempt int,
+	waited time.Duration,
+	reason string,
+	resp *http.Response,
+	err error,
+) {
+	trace, ok := req.Context().Value(traceContextKey{}).(hlpr.TraceContext)
+	if !ok {
+		return
+	}
+	end := time.Now()
+	fields := map[string]string{
+		"method":       req.Method,
+		"url":          traceURL(req.URL),
+		"attempt":      strconv.Itoa(attempt),
+		"parentSpanId": trace.ParentId,
+	}
+	rootFields(req.Context(), fields)
+	if attempt > 1 {
+		fields["backoff"] = waited.String()
+		fields["retryReason"] = reason
+	}
+	success := false
+	if err != nil {
+		fields["error"] = err.Error()
+	} else {
+		fields["statusCode"] = strconv.Itoa(resp.StatusCode)
+		success = resp.StatusCode > 199 && resp.StatusCode < 300
+	}
+	client.tracer.TraceDependency(
+		req.Context(),
+		trace.SpanId,
+		"http",
+		req.URL.Hostname(),
+		fmt.Sprintf("%s %s", req.Method, req.URL.RequestURI()),
+		success,
+		start,
+		end,
+		fields,
+	)
+}
+
+// retryMiddleware runs the attempts of the call through the rest of the
+// chain, see send
+func (client *HttpClient) retryMiddleware(next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		return client.send(req, next)
+	}
+}
@@ -0,0 +1,145 @@
+package gottp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	tries := 0
+	failOnce := func() (*http.Response, error) {
+		tries++
+		if tries < 2 {
+			return &http.Response{
+				StatusCode: 500,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	calls := []string{}
+	record := func(name string) RoundTripMiddleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("traceparent") == "" {
+					t.Errorf("%s ran before the tracing middleware", name)
+				}
+				calls = append(calls, name)
+				return next(req)
+			}
+		}
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	optn.Middlewares = []RoundTripMiddleware{record("options")}
+	optn.AttemptMiddlewares = []RoundTripMiddleware{record("attempt")}
+	client = client.WithOptions(optn).Use(record("use"))
+
+	res, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("invalid status code %d", res.StatusCode)
+	}
+	expected := []string{"options", "use", "attempt", "attempt"}
+	if len(calls) != len(expected) {
+		t.Fatalf("unexpected middleware calls %v", calls)
+	}
+	for idx := range expected {
+		if calls[idx] != expected[idx] {
+			t.Fatalf("unexpected middleware calls %v", calls)
+		}
+	}
+}
+
+func TestRootTrace(t *testing.T) {
+	traceparent := ""
+	mock := &MockClient{
+		handler: func(req *http.Request) (*http.Response, error) {
+			traceparent = req.Header.Get("traceparent")
+			return &http.Response{StatusCode: 200}, nil
+		},
+	}
+	tracer := &recordingTrace{}
+	client := NewHttpClientWithClientProvider(mock, tracer, nil, "", "", "")
+	empty := NewHttpClientWithClientProvider(
+		mock,
+		&zeroTrace{recordingTrace: tracer},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.RootSampler = func(req *http.Request) bool {
+		return req.Method != "GET"
+	}
+	empty = empty.WithOptions(optn)
+
+	if _, err := empty.Get(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	ver, tid, sid, flg, err := hlpr.ParseTraceparent(traceparent)
+	if err != nil || ver != "00" || flg != "00" {
+		t.Fatalf("expected unsampled root traceparent, got %q %v", traceparent, err)
+	}
+	call := tracer.spans[len(tracer.spans)-1]
+	if call.spanId == sid ||
+		call.fields["traceId"] != tid ||
+		call.fields["traceFlags"] != "00" ||
+		call.fields["rootTrace"] != "true" {
+		t.Fatalf("root trace not reported, got %v", call)
+	}
+	attempt := tracer.spans[len(tracer.spans)-2]
+	if attempt.spanId != sid ||
+		attempt.fields["parentSpanId"] != call.spanId ||
+		attempt.fields["traceId"] != tid {
+		t.Fatalf("unexpected attempt span %v", attempt)
+	}
+
+	if _, err := empty.Post(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if _, _, _, flg, _ := hlpr.ParseTraceparent(traceparent); flg != "01" {
+		t.Fatalf("expected sampled root traceparent, got %q", traceparent)
+	}
+
+	tracer.spans = nil
+	if _, err := client.Get(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if !strings.HasPrefix(traceparent, "00-0af7651916cd43dd8448eb211c80319c-") ||
+		tracer.spans[0].fields["rootTrace"] != "" {
+		t.Fatalf("valid trace should be kept, got %q", traceparent)
+	}
+}
+
+// zeroTrace records dependencies while extracting an invalid all zero trace
+type zeroTrace struct {
+	*recordingTrace
+}
+
+func (m *zeroTrace) ExtractTraceInfo(
+	ctx context.Context,
+) (ver, tid, pid, rid, flg string) {
+	return "00", "00000000000000000000000000000000", "", "b7ad6b7169203331", "01"
+}
@@ -0,0 +1,140 @@
+package gottp
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// MultipartForm multipart/form-data body, streamed part by part so files are
+// never buffered in memory
+type MultipartForm struct {
+	Fields url.Values
+	Files  []MultipartFile
+}
+
+// MultipartFile file part of a multipart form. The content is read from Open
+// when set, which is called again for every retry, and from Reader
+// otherwise, a Reader is rewound for retries when it implements io.Seeker
+// and makes the request non retriable when it does not.
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Reader      io.Reader
+	Open        func() (io.ReadCloser, error)
+}
+
+// replayable reports whether the form can be streamed again for a retry
+func (form *MultipartForm) replayable() bool {
+	for _, file := range form.Files {
+		if file.Open != nil {
+			continue
+		}
+		if _, ok := file.Reader.(io.Seeker); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// factory streams the form through a pipe every time it is invoked
+func (form *MultipartForm) factory(boundary string) bodyFactory {
+	var invocations int32
+	return func() (io.ReadCloser, error) {
+		rewind := atomic.AddInt32(&invocations, 1) > 1
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(form.write(writer, boundary, rewind))
+		}()
+		return reader, nil
+	}
+}
+
+func (form *MultipartForm) write(
+	dst io.Writer,
+	boundary string,
+	rewind bool,
+) error {
+	writer := multipart.NewWriter(dst)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for key, values := range form.Fields {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range form.Files {
+		if err := file.write(writer, rewind); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func (file MultipartFile) write(writer *multipart.Writer, rewind bool) error {
+	content, err := file.open(rewind)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := textproto.MIMEHeader{}
+	header.Set(
+		"Content-Disposition",
+		fmt.Sprintf(
+			`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.FieldName),
+			escapeQuotes(file.FileName),
+		),
+	)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, content)
+	return err
+}
+
+func (file MultipartFile) open(rewind bool) (io.ReadCloser, error) {
+	if file.Open != nil {
+		return file.Open()
+	}
+	if file.Reader == nil {
+		return nil, fmt.Errorf("multipart file %s has no content", file.FieldName)
+	}
+	if rewind {
+		seeker, ok := file.Reader.(io.Seeker)
+		if !ok {
+			return nil, ErrBodyNotReplayable
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(file.Reader), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// multipartBoundary a random boundary, fixed for the request so every
+// attempt matches the Content-Type header
+func multipartBoundary() string {
+	return multipart.NewWriter(io.Discard).Boundary()
+}
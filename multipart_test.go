@@ -0,0 +1,239 @@
+package gottp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMultipart decodes the form fields and files of a multipart request
+func readMultipart(req *http.Request) (map[string]string, error) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(req.Body, params["boundary"])
+	parts := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		byts, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		key := part.FormName()
+		if part.FileName() != "" {
+			key += ":" + part.FileName() + ":" + part.Header.Get("Content-Type")
+		}
+		parts[key] = string(byts)
+	}
+}
+
+func TestPostMultipartRetry(t *testing.T) {
+	attempts := []map[string]string{}
+	failOnce := func(req *http.Request) (*http.Response, error) {
+		parts, err := readMultipart(req)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, parts)
+		status := 200
+		if len(attempts) < 2 {
+			status = 503
+		}
+		return &http.Response{
+			StatusCode: status,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	res, err := client.PostMultipart(
+		context.TODO(),
+		nil,
+		&MultipartForm{
+			Fields: url.Values{"name": []string{"report"}},
+			Files: []MultipartFile{
+				{
+					FieldName:   "file",
+					FileName:    "report.csv",
+					ContentType: "text/csv",
+					Reader:      bytes.NewReader([]byte("a,b\n1,2\n")),
+				},
+				{
+					FieldName: "notes",
+					FileName:  "notes.txt",
+					Open: func() (io.ReadCloser, error) {
+						return io.NopCloser(strings.NewReader("notes")), nil
+					},
+				},
+			},
+		},
+		"http://a.test/upload",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 || len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+	for idx, parts := range attempts {
+		if parts["name"] != "report" ||
+			parts["file:report.csv:text/csv"] != "a,b\n1,2\n" ||
+			parts["notes:notes.txt:application/octet-stream"] != "notes" {
+			t.Errorf("attempt %d sent %v", idx, parts)
+		}
+	}
+}
+
+func TestPutMultipartNotReplayable(t *testing.T) {
+	tries := 0
+	unavailable := func(req *http.Request) (*http.Response, error) {
+		tries++
+		if _, err := readMultipart(req); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: 503,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: unavailable,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	res, err := client.PutMultipart(
+		context.TODO(),
+		nil,
+		&MultipartForm{
+			Files: []MultipartFile{
+				{
+					FieldName: "file",
+					FileName:  "stream.bin",
+					Reader:    io.MultiReader(strings.NewReader("stream")),
+				},
+			},
+		},
+		"http://a.test/upload",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 503 || tries != 1 {
+		t.Fatalf("non replayable body retried, %d attempts", tries)
+	}
+}
+
+// signalClose reader reporting when it is closed
+type signalClose struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (body *signalClose) Close() error {
+	close(body.closed)
+	return nil
+}
+
+func TestRejectedRequestsCloseBodies(t *testing.T) {
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: func() (*http.Response, error) {
+				return &http.Response{StatusCode: 500}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.Enabled = false
+	optn.CircuitBreaker = CircuitBreakerPolicy{
+		Enabled:          true,
+		FailureThreshold: 1,
+		Window:           time.Minute,
+		HalfOpenProbes:   1,
+		OpenDuration:     time.Minute,
+	}
+	client = client.WithOptions(optn)
+	client.Get(context.TODO(), nil, "http://a.test", nil)
+
+	file := &signalClose{
+		Reader: strings.NewReader("content"),
+		closed: make(chan struct{}),
+	}
+	_, err := client.PostMultipart(
+		context.TODO(),
+		nil,
+		&MultipartForm{
+			Files: []MultipartFile{{
+				FieldName: "file",
+				FileName:  "file.txt",
+				Open: func() (io.ReadCloser, error) {
+					return file, nil
+				},
+			}},
+		},
+		"http://a.test",
+		nil,
+	)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	select {
+	case <-file.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("multipart writer leaked the file of a rejected request")
+	}
+
+	stream := &signalClose{
+		Reader: strings.NewReader("content"),
+		closed: make(chan struct{}),
+	}
+	_, err = client.NewRequest("POST", "http://a.test").
+		Stream(StreamBody{Reader: stream, Length: 7}).
+		Do(context.TODO())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	select {
+	case <-stream.closed:
+	default:
+		t.Fatalf("stream body of a rejected request was not closed")
+	}
+}
@@ -1,9 +1,47 @@
 package gottp
 
-import "time"
+import (
+	"net/http"
+	"time"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
 
 type ClientOptions struct {
-  Retry RetryPolicy
+	Retry RetryPolicy
+	// Timeout bounds a single attempt, including reading the response body,
+	// zero disables it
+	Timeout time.Duration
+	// OverallDeadline bounds the whole call across all attempts and backoffs,
+	// zero disables it
+	OverallDeadline time.Duration
+	CircuitBreaker  CircuitBreakerPolicy
+	Bulkhead        BulkheadPolicy
+	RateLimit       RateLimitPolicy
+	Hedging         HedgingPolicy
+	Compression     CompressionPolicy
+	// Propagator writes the trace headers of every request, the W3C
+	// traceparent when nil, use hlpr.CompositePropagator for several formats
+	Propagator hlpr.Propagator
+	// RootSampler decides whether the trace started for a call made outside
+	// of any trace is sampled, every such trace is sampled when nil. The
+	// spans of these calls report the new trace through the traceId,
+	// traceFlags and rootTrace fields.
+	RootSampler func(req *http.Request) bool
+	// Middlewares wrap every call, after the built in tracing and before the
+	// built in retry
+	Middlewares []RoundTripMiddleware
+	// AttemptMiddlewares wrap every attempt, inside the built in retry
+	AttemptMiddlewares []RoundTripMiddleware
+	// ErrorDecoder converts non 2xx responses into errors for the typed
+	// helpers such as GetJSON, a *StatusError is returned when nil
+	ErrorDecoder ErrorDecoder
+	// ErrorOnStatus returns an *HTTPError instead of the response for non
+	// 2xx statuses
+	ErrorOnStatus bool
+	// MaxResponseBodySize fails reads of response bodies larger than this
+	// with a *BodyTooLargeError, zero leaves them unbounded
+	MaxResponseBodySize int64
 }
 
 type RetryPolicy struct {
@@ -11,21 +49,125 @@ type RetryPolicy struct {
 	RetriableCodes []int
 	RetryCount     int
 	InitialBackoff time.Duration
+	// Backoff selects the built in backoff strategy, exponential by default
+	Backoff BackoffKind
+	// BackoffStrategy custom strategy taking precedence over Backoff
+	BackoffStrategy BackoffStrategy
+	// MaxBackoff caps a single wait between attempts, zero leaves it uncapped
+	MaxBackoff time.Duration
+	// MaxElapsed stops retrying once the next attempt would start later than
+	// this after the first one, zero disables it
+	MaxElapsed time.Duration
+	// RetryTooManyRequests adds 429 to the codes retried by the default
+	// classifier
+	RetryTooManyRequests bool
+	// MaxRetryAfter caps the delay requested by a server through Retry-After
+	// or X-RateLimit-Reset, zero leaves it uncapped
+	MaxRetryAfter time.Duration
+	// Classifier decides which attempts are retried, DefaultRetryClassifier
+	// over RetriableCodes is used when nil
+	Classifier RetryClassifier
+}
+
+// CircuitBreakerPolicy per host circuit breaker, the circuit opens once
+// FailureThreshold attempts failed with a transport error or a 5xx status
+// within Window, stays open for OpenDuration and then lets HalfOpenProbes
+// attempts through to decide whether to close again
+type CircuitBreakerPolicy struct {
+	Enabled          bool
+	FailureThreshold int
+	Window           time.Duration
+	HalfOpenProbes   int
+	OpenDuration     time.Duration
+}
+
+// BulkheadPolicy limits the requests in flight per host and, when
+// MaxConcurrentGlobal is set, across all hosts. Requests beyond the limit
+// wait in a queue of MaxQueue for at most QueueTimeout before failing with
+// ErrBulkheadFull, a zero QueueTimeout waits as long as the context allows.
+type BulkheadPolicy struct {
+	Enabled              bool
+	MaxConcurrentPerHost int
+	MaxConcurrentGlobal  int
+	MaxQueue             int
+	QueueTimeout         time.Duration
+}
+
+// RateLimitPolicy token bucket limits applied before every attempt, Rate is
+// the global limit in requests per second and Rules add limits for the
+// requests they match. Attempts wait for a token unless Reject is set, in
+// which case they fail with ErrRateLimited, hedges never wait and are only
+// sent when a token is available. Adaptive makes the bucket of the
+// matching rule follow the quota the server reports through
+// X-RateLimit-Remaining and X-RateLimit-Reset, waiting attempts sleep until
+// the reset once it is exhausted.
+type RateLimitPolicy struct {
+	Enabled  bool
+	Rate     float64
+	Burst    int
+	Rules    []RateLimitRule
+	Reject   bool
+	Adaptive bool
+}
+
+// RateLimitRule limit shared by the requests matching the host and path
+// patterns, using path.Match syntax, empty patterns match everything and only
+// the first matching rule applies
+type RateLimitRule struct {
+	Host  string
+	Path  string
+	Rate  float64
+	Burst int
+}
+
+// HedgingPolicy fires up to MaxHedges additional copies of an idempotent
+// attempt while none of the previous ones has answered within the hedge
+// delay, the delay is the Percentile (0 to 1) of recently observed latencies
+// when set and enough samples exist, Delay otherwise
+type HedgingPolicy struct {
+	Enabled    bool
+	MaxHedges  int
+	Delay      time.Duration
+	Percentile float64
+}
+
+// CompressionPolicy compresses in memory request bodies of at least MinSize
+// bytes with RequestEncoding, an empty RequestEncoding only decodes, and
+// decodes every response compressed with a known coding. gzip and deflate
+// are built in, Codecs adds further codings such as zstd or br.
+type CompressionPolicy struct {
+	Enabled         bool
+	RequestEncoding string
+	MinSize         int
+	Codecs          []Codec
 }
 
 func DefaultOptions() *ClientOptions {
-  return &ClientOptions{
-    Retry: RetryPolicy{
-      Enabled: true,
-      RetriableCodes: []int {
-        408,
-        500,
-        502,
-        503,
-        504,
-      },
-      RetryCount: 5,
-      InitialBackoff: 100 * time.Millisecond,
-    },
-  }
+	return &ClientOptions{
+		Retry: RetryPolicy{
+			Enabled: true,
+			RetriableCodes: []int{
+				408,
+				500,
+				502,
+				503,
+				504,
+			},
+			RetryCount:     5,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxRetryAfter:  30 * time.Second,
+		},
+		CircuitBreaker: CircuitBreakerPolicy{
+			Enabled:          false,
+			FailureThreshold: 5,
+			Window:           30 * time.Second,
+			HalfOpenProbes:   1,
+			OpenDuration:     30 * time.Second,
+		},
+		Compression: CompressionPolicy{
+			Enabled:         false,
+			RequestEncoding: "gzip",
+			MinSize:         1024,
+		},
+	}
 }
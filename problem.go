@@ -0,0 +1,141 @@
+package gottp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxProblemSize bytes read when decoding a problem document
+const maxProblemSize = 64 << 10
+
+// ProblemDetails RFC 7807 problem document, members other than the standard
+// ones are kept undecoded in Extensions
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]json.RawMessage
+}
+
+func (problem *ProblemDetails) Error() string {
+	msg := problem.Title
+	if msg == "" {
+		msg = problem.Type
+	}
+	if problem.Detail != "" {
+		msg += ": " + problem.Detail
+	}
+	if problem.Status != 0 {
+		msg = fmt.Sprintf("%d %s", problem.Status, msg)
+	}
+	return msg
+}
+
+// Extension decodes the extension member into value, reporting whether it
+// exists
+func (problem *ProblemDetails) Extension(
+	name string,
+	value interface{},
+) (bool, error) {
+	raw, ok := problem.Extensions[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, value)
+}
+
+func (problem *ProblemDetails) UnmarshalJSON(byts []byte) error {
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(byts, &members); err != nil {
+		return err
+	}
+	standard := []struct {
+		name  string
+		value interface{}
+	}{
+		{"type", &problem.Type},
+		{"title", &problem.Title},
+		{"status", &problem.Status},
+		{"detail", &problem.Detail},
+		{"instance", &problem.Instance},
+	}
+	for _, member := range standard {
+		raw, ok := members[member.name]
+		if !ok {
+			continue
+		}
+		// members of the wrong type are ignored as RFC 7807 requires
+		json.Unmarshal(raw, member.value)
+		delete(members, member.name)
+	}
+	if len(members) > 0 {
+		problem.Extensions = members
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	return nil
+}
+
+func (problem *ProblemDetails) MarshalJSON() ([]byte, error) {
+	members := map[string]interface{}{}
+	for name, raw := range problem.Extensions {
+		members[name] = raw
+	}
+	members["type"] = problem.Type
+	if problem.Title != "" {
+		members["title"] = problem.Title
+	}
+	if problem.Status != 0 {
+		members["status"] = problem.Status
+	}
+	if problem.Detail != "" {
+		members["detail"] = problem.Detail
+	}
+	if problem.Instance != "" {
+		members["instance"] = problem.Instance
+	}
+	return json.Marshal(members)
+}
+
+// isProblem reports whether the response carries a problem document
+func isProblem(header http.Header) bool {
+	media, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && media == "application/problem+json"
+}
+
+// parseProblem decodes a problem document, nil when the content type is not
+// application/problem+json or the body is not a valid document
+func parseProblem(header http.Header, body []byte) *ProblemDetails {
+	if !isProblem(header) {
+		return nil
+	}
+	problem := &ProblemDetails{}
+	if err := problem.UnmarshalJSON(body); err != nil {
+		return nil
+	}
+	return problem
+}
+
+// Problem decodes the body as an RFC 7807 problem document, returning nil
+// without reading the body when the response is not
+// application/problem+json
+func (resp *Response) Problem() (*ProblemDetails, error) {
+	if !isProblem(resp.Header) || resp.Body == nil {
+		return nil, nil
+	}
+	byts, err := io.ReadAll(io.LimitReader(resp.Body, maxProblemSize))
+	if err != nil {
+		return nil, err
+	}
+	problem := &ProblemDetails{}
+	if err := problem.UnmarshalJSON(byts); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
@@ -0,0 +1,90 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testProblem = `{
+	"type": "https://example.com/probs/out-of-credit",
+	"title": "You do not have enough credit.",
+	"status": 403,
+	"detail": "Your current balance is 30, but that costs 50.",
+	"instance": "/account/12345/msgs/abc",
+	"balance": 30
+}`
+
+func problemResponse() (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 403,
+		Header: http.Header{
+			"Content-Type": []string{"application/problem+json; charset=utf-8"},
+		},
+		Body: io.NopCloser(strings.NewReader(testProblem)),
+	}, nil
+}
+
+func TestResponseProblem(t *testing.T) {
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: problemResponse,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	res, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	problem, err := res.Problem()
+	if err != nil || problem == nil {
+		t.Fatalf("problem not decoded: %v", err)
+	}
+	if problem.Status != 403 ||
+		problem.Type != "https://example.com/probs/out-of-credit" ||
+		problem.Instance != "/account/12345/msgs/abc" {
+		t.Fatalf("unexpected problem %+v", problem)
+	}
+	balance := 0
+	if ok, err := problem.Extension("balance", &balance); !ok || err != nil || balance != 30 {
+		t.Fatalf("extension not decoded %v %v %d", ok, err, balance)
+	}
+}
+
+func TestErrorOnStatusProblem(t *testing.T) {
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: problemResponse,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.ErrorOnStatus = true
+	client = client.WithOptions(optn)
+
+	_, err := client.PostBody(
+		context.TODO(),
+		nil,
+		jsonDTO(map[string]int{"amount": 50}),
+		"http://a.test",
+		nil,
+	)
+	var problem *ProblemDetails
+	if !errors.As(err, &problem) || problem.Title != "You do not have enough credit." {
+		t.Fatalf("expected problem error, got %v", err)
+	}
+	if !IsForbidden(err) {
+		t.Fatalf("status not reported")
+	}
+}
@@ -0,0 +1,90 @@
+package gottp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+func TestPropagators(t *testing.T) {
+	trace := hlpr.TraceContext{
+		Version:  "00",
+		TraceId:  "5759e988bd862e3fe1be46a994272793",
+		ParentId: "b7ad6b7169203331",
+		SpanId:   "53995c3f42cd8ad8",
+		Flags:    "01",
+	}
+	headers := http.Header{}
+	hlpr.CompositePropagator{
+		hlpr.W3CPropagator{},
+		hlpr.B3SinglePropagator{},
+		hlpr.B3MultiPropagator{},
+		hlpr.JaegerPropagator{},
+		hlpr.XRayPropagator{},
+	}.Inject(trace, headers)
+
+	expected := map[string]string{
+		"traceparent":       "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01",
+		"b3":                "5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-1-b7ad6b7169203331",
+		"X-B3-TraceId":      "5759e988bd862e3fe1be46a994272793",
+		"X-B3-SpanId":       "53995c3f42cd8ad8",
+		"X-B3-ParentSpanId": "b7ad6b7169203331",
+		"X-B3-Sampled":      "1",
+		"uber-trace-id":     "5759e988bd862e3fe1be46a994272793:53995c3f42cd8ad8:0:1",
+		"X-Amzn-Trace-Id":   "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	}
+	for key, value := range expected {
+		if headers.Get(key) != value {
+			t.Fatalf("unexpected %s %q", key, headers.Get(key))
+		}
+	}
+
+	trace.Flags = "00"
+	trace.ParentId = ""
+	hlpr.B3SinglePropagator{}.Inject(trace, headers)
+	if headers.Get("b3") != "5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-0" {
+		t.Fatalf("unexpected unsampled b3 %q", headers.Get("b3"))
+	}
+}
+
+func TestClientPropagator(t *testing.T) {
+	var received http.Header
+	tracer := &recordingTrace{}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				received = req.Header
+				return &http.Response{StatusCode: 200}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Propagator = hlpr.CompositePropagator{
+		hlpr.B3MultiPropagator{},
+		hlpr.JaegerPropagator{},
+	}
+	client = client.WithOptions(optn)
+	if _, err := client.Get(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if received.Get("traceparent") != "" {
+		t.Fatalf("unexpected traceparent %q", received.Get("traceparent"))
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected the attempt and call spans, got %v", tracer.spans)
+	}
+	attempt, call := tracer.spans[0], tracer.spans[1]
+	if received.Get("X-B3-TraceId") != "0af7651916cd43dd8448eb211c80319c" ||
+		received.Get("X-B3-ParentSpanId") != call.spanId ||
+		received.Get("X-B3-SpanId") != attempt.spanId ||
+		received.Get("uber-trace-id") == "" {
+		t.Fatalf("unexpected headers %v for spans %v", received, tracer.spans)
+	}
+}
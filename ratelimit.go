@@ -0,0 +1,297 @@
+package gottp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiters the token buckets of a client, a global one and one per rule
+type rateLimiters struct {
+	policy RateLimitPolicy
+	global *tokenBucket
+	rules  []*tokenBucket
+}
+
+func newRateLimiters(policy RateLimitPolicy) *rateLimiters {
+	if !policy.Enabled {
+		return nil
+	}
+	limiters := &rateLimiters{
+		policy: policy,
+		global: newTokenBucket(policy.Rate, policy.Burst),
+		rules:  make([]*tokenBucket, len(policy.Rules)),
+	}
+	for idx, rule := range policy.Rules {
+		limiters.rules[idx] = newTokenBucket(rule.Rate, rule.Burst)
+	}
+	return limiters
+}
+
+// buckets the buckets limiting the request, the global one and the bucket of
+// the first matching rule
+func (limiters *rateLimiters) buckets(req *http.Request) []*tokenBucket {
+	if limiters == nil {
+		return nil
+	}
+	buckets := []*tokenBucket{}
+	if limiters.global != nil {
+		buckets = append(buckets, limiters.global)
+	}
+	if bucket := limiters.rule(req); bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// rule the bucket of the first rule matching the request
+func (limiters *rateLimiters) rule(req *http.Request) *tokenBucket {
+	for idx, rule := range limiters.policy.Rules {
+		if rule.matches(req) {
+			return limiters.rules[idx]
+		}
+	}
+	return nil
+}
+
+// wait takes a token from every bucket limiting the request, blocking until
+// they are available or rejecting with ErrRateLimited
+func (limiters *rateLimiters) wait(
+	ctx context.Context,
+	req *http.Request,
+) error {
+	if limiters == nil {
+		return nil
+	}
+	return limiters.acquire(ctx, req, !limiters.policy.Reject)
+}
+
+// take takes a token from every bucket limiting the request without
+// waiting, rejecting with ErrRateLimited when one is exhausted
+func (limiters *rateLimiters) take(req *http.Request) error {
+	return limiters.acquire(req.Context(), req, false)
+}
+
+// acquire takes a token from every bucket limiting the request, the tokens
+// already taken are handed back when a later bucket fails
+func (limiters *rateLimiters) acquire(
+	ctx context.Context,
+	req *http.Request,
+	block bool,
+) error {
+	buckets := limiters.buckets(req)
+	for idx, bucket := range buckets {
+		var err error
+		if block {
+			err = bucket.wait(ctx)
+		} else {
+			err = bucket.take()
+		}
+		if err != nil {
+			for _, taken := range buckets[:idx] {
+				taken.giveBack()
+			}
+			if err == ErrRateLimited {
+				err = fmt.Errorf("%w for host %s", err, req.URL.Host)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// observe adapts the bucket of the rule matching the request to the quota
+// reported by the server, the global bucket is shared by every host and is
+// never adapted to the quota of one of them
+func (limiters *rateLimiters) observe(req *http.Request, resp *http.Response) {
+	if limiters == nil || !limiters.policy.Adaptive || resp == nil {
+		return
+	}
+	val := strings.TrimSpace(resp.Header.Get("X-RateLimit-Remaining"))
+	if val == "" {
+		return
+	}
+	remaining, err := strconv.ParseFloat(val, 64)
+	if err != nil || remaining < 0 {
+		return
+	}
+	reset, ok := rateLimitReset(resp, time.Now())
+	if bucket := limiters.rule(req); bucket != nil {
+		bucket.adapt(remaining, reset, ok)
+	}
+}
+
+// rateLimitReset the time until the quota reported by X-RateLimit-Reset is
+// restored
+func rateLimitReset(
+	resp *http.Response,
+	now time.Time,
+) (time.Duration, bool) {
+	val := strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset"))
+	if val == "" {
+		return 0, false
+	}
+	reset, err := strconv.ParseFloat(val, 64)
+	if err != nil || reset < 0 {
+		return 0, false
+	}
+	// providers either send the seconds until the reset or the unix time of
+	// the reset, anything larger than a few years of seconds is a timestamp
+	if reset > rateLimitEpochThreshold {
+		at := time.Unix(0, int64(reset*float64(time.Second)))
+		return nonNegative(at.Sub(now)), true
+	}
+	return time.Duration(reset * float64(time.Second)), true
+}
+
+const rateLimitEpochThreshold = 100000000
+
+func (rule RateLimitRule) matches(req *http.Request) bool {
+	if rule.Host != "" {
+		if ok, _ := path.Match(rule.Host, req.URL.Hostname()); !ok {
+			return false
+		}
+	}
+	if rule.Path != "" {
+		if ok, _ := path.Match(rule.Path, req.URL.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenBucket refills rate tokens per second up to burst, while adapted the
+// rate follows the quota reported by the server until its reset
+type tokenBucket struct {
+	mtx          sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	adaptedRate  float64
+	adaptedUntil time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accrued since the last refill, at the adapted rate
+// until the reset and at the configured rate after it, must be called
+// holding the lock
+func (bucket *tokenBucket) refill(now time.Time) {
+	if bucket.last.Before(bucket.adaptedUntil) {
+		end := bucket.adaptedUntil
+		if now.Before(end) {
+			end = now
+		}
+		bucket.tokens += end.Sub(bucket.last).Seconds() * bucket.adaptedRate
+		bucket.last = end
+	}
+	if now.After(bucket.last) {
+		bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+		bucket.last = now
+	}
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// delay until the missing tokens have accrued, waiting out an exhausted
+// adapted quota until its reset, must be called holding the lock
+func (bucket *tokenBucket) delay(missing float64, now time.Time) time.Duration {
+	var delay time.Duration
+	if now.Before(bucket.adaptedUntil) {
+		window := bucket.adaptedUntil.Sub(now)
+		if bucket.adaptedRate > 0 &&
+			missing/bucket.adaptedRate <= window.Seconds() {
+			return time.Duration(missing / bucket.adaptedRate * float64(time.Second))
+		}
+		missing -= window.Seconds() * bucket.adaptedRate
+		delay = window
+	}
+	return delay + time.Duration(missing/bucket.rate*float64(time.Second))
+}
+
+// take a token without waiting
+func (bucket *tokenBucket) take() error {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	bucket.refill(time.Now())
+	if bucket.tokens < 1 {
+		return ErrRateLimited
+	}
+	bucket.tokens--
+	return nil
+}
+
+// wait reserves a token and waits until it is available, handing it back
+// when the context is done first
+func (bucket *tokenBucket) wait(ctx context.Context) error {
+	bucket.mtx.Lock()
+	now := time.Now()
+	bucket.refill(now)
+	bucket.tokens--
+	tokens := bucket.tokens
+	var delay time.Duration
+	if tokens < 0 {
+		delay = bucket.delay(-tokens, now)
+	}
+	bucket.mtx.Unlock()
+	if tokens >= 0 {
+		return nil
+	}
+
+	if err := sleepContext(ctx, delay); err != nil {
+		bucket.giveBack()
+		return err
+	}
+	return nil
+}
+
+func (bucket *tokenBucket) giveBack() {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	bucket.tokens++
+}
+
+// adapt limits the bucket to the remaining quota, spreading it until the
+// reset when it is known
+func (bucket *tokenBucket) adapt(
+	remaining float64,
+	reset time.Duration,
+	hasReset bool,
+) {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	now := time.Now()
+	bucket.refill(now)
+	if bucket.tokens > remaining {
+		bucket.tokens = remaining
+	}
+	if hasReset && reset > 0 {
+		rate := remaining / reset.Seconds()
+		if rate < bucket.rate {
+			bucket.adaptedRate = rate
+			bucket.adaptedUntil = now.Add(reset)
+		} else {
+			bucket.adaptedUntil = time.Time{}
+		}
+	}
+}
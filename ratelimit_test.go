@@ -0,0 +1,169 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRejectsPerRule(t *testing.T) {
+	ok := func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: ok,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.RateLimit = RateLimitPolicy{
+		Enabled: true,
+		Reject:  true,
+		Rules: []RateLimitRule{
+			{Host: "a.test", Path: "/limited/*", Rate: 1, Burst: 1},
+		},
+	}
+	client = client.WithOptions(optn)
+
+	if _, err := client.Get(context.TODO(), nil, "http://a.test/limited/1", nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err := client.Get(context.TODO(), nil, "http://a.test/limited/2", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limit, got %v", err)
+	}
+	if _, err := client.Get(context.TODO(), nil, "http://a.test/open", nil); err != nil {
+		t.Fatalf("unmatched route limited: %v", err)
+	}
+}
+
+func TestRateLimitWaitsForToken(t *testing.T) {
+	ok := func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: ok,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.RateLimit = RateLimitPolicy{
+		Enabled: true,
+		Rate:    20,
+		Burst:   1,
+	}
+	client = client.WithOptions(optn)
+
+	start := time.Now()
+	for idx := 0; idx < 3; idx++ {
+		if _, err := client.Get(context.TODO(), nil, "http://a.test", nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("requests were not paced, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := client.Get(ctx, nil, "http://a.test", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
+
+func TestRateLimitAdapts(t *testing.T) {
+	bucket := newTokenBucket(100, 10)
+	bucket.adapt(0, time.Minute, true)
+	if err := bucket.take(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("exhausted quota not applied, got %v", err)
+	}
+}
+
+func TestRateLimitWaitsForAdaptedReset(t *testing.T) {
+	bucket := newTokenBucket(1000, 1)
+	bucket.adapt(0, 30*time.Millisecond, true)
+	start := time.Now()
+	if err := bucket.wait(context.TODO()); err != nil {
+		t.Fatalf("blocking wait failed on an exhausted quota: %v", err)
+	}
+	if waited := time.Since(start); waited < 25*time.Millisecond {
+		t.Fatalf("wait returned before the reset, after %v", waited)
+	}
+
+	bucket.adapt(0, time.Minute, true)
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context to end the wait, got %v", err)
+	}
+}
+
+func TestRateLimitAdaptsRuleOnly(t *testing.T) {
+	limiters := newRateLimiters(RateLimitPolicy{
+		Enabled: true,
+		Rate:    1000,
+		Burst:   10,
+		Rules: []RateLimitRule{
+			{Host: "a.test", Rate: 1000, Burst: 10},
+		},
+		Reject:   true,
+		Adaptive: true,
+	})
+	reqA, _ := http.NewRequest("GET", "http://a.test/", nil)
+	reqB, _ := http.NewRequest("GET", "http://b.test/", nil)
+	limiters.observe(reqA, &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {"60"},
+		},
+	})
+
+	if err := limiters.wait(context.TODO(), reqA); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected the host's quota to apply, got %v", err)
+	}
+	if err := limiters.wait(context.TODO(), reqB); err != nil {
+		t.Fatalf("another host was throttled by the quota: %v", err)
+	}
+}
+
+func TestRateLimitGivesBackTakenTokens(t *testing.T) {
+	limiters := newRateLimiters(RateLimitPolicy{
+		Enabled: true,
+		Rate:    0.001,
+		Burst:   1,
+		Rules: []RateLimitRule{
+			{Host: "a.test", Rate: 0.001, Burst: 1},
+		},
+		Reject: true,
+	})
+	reqA, _ := http.NewRequest("GET", "http://a.test/", nil)
+	reqB, _ := http.NewRequest("GET", "http://b.test/", nil)
+	limiters.rules[0].take()
+
+	if err := limiters.wait(context.TODO(), reqA); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected the rule to reject, got %v", err)
+	}
+	if err := limiters.wait(context.TODO(), reqB); err != nil {
+		t.Fatalf("global token taken by the rejected request: %v", err)
+	}
+}
@@ -1,9 +1,16 @@
 package gottp
 
 import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
 type Response http.Response
@@ -31,3 +38,168 @@ func (resp *Response) UnmarshalXml(data interface{}) error {
 	}
 	return nil
 }
+
+// DecodeStream reads the body item by item without buffering it, the body is
+// either a JSON array or newline delimited JSON. Every item is passed to fn,
+// an error returned by fn stops the stream and is returned. The body is
+// closed once done.
+func (resp *Response) DecodeStream(fn func(item json.RawMessage) error) error {
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first != '[' {
+		for {
+			var item json.RawMessage
+			if err := decoder.Decode(&item); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+// DecodeEach decodes every item of a streamed JSON array or newline delimited
+// JSON body into T, see Response.DecodeStream
+func DecodeEach[T any](resp *Response, fn func(item T) error) error {
+	return resp.DecodeStream(func(raw json.RawMessage) error {
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+		return fn(item)
+	})
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch char {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return char, reader.UnreadByte()
+	}
+}
+
+// SaveTo streams the body into the file at path, writing a temporary file
+// next to it that is renamed once complete so the path never holds a partial
+// download. The file gets the permissions os.Create would give it and the
+// body is closed once done.
+func (resp *Response) SaveTo(path string) (int64, error) {
+	defer resp.Body.Close()
+	tmp, err := createTemp(path)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(tmp, resp.Body)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return written, err
+	}
+	return written, nil
+}
+
+// createTemp creates a uniquely named temporary file next to path, unlike
+// os.CreateTemp with the umasked 0666 permissions of os.Create
+func createTemp(path string) (*os.File, error) {
+	raw := make([]byte, 8)
+	for try := 0; ; try++ {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		name := filepath.Join(
+			filepath.Dir(path),
+			"."+filepath.Base(path)+"."+hex.EncodeToString(raw)+".tmp",
+		)
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if os.IsExist(err) && try < 100 {
+			continue
+		}
+		return file, err
+	}
+}
+
+// BodyTooLargeError returned by reads of a response body exceeding
+// ClientOptions.MaxResponseBodySize
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (err *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the limit of %d bytes", err.Limit)
+}
+
+// maxBodyReader fails reads past the limit instead of silently truncating
+// the body
+type maxBodyReader struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func limitBody(body io.ReadCloser, limit int64) io.ReadCloser {
+	if body == nil || limit <= 0 {
+		return body
+	}
+	return &maxBodyReader{
+		ReadCloser: body,
+		limit:      limit,
+		remaining:  limit,
+	}
+}
+
+func (body *maxBodyReader) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, &BodyTooLargeError{Limit: body.limit}
+	}
+	// read one byte past the limit to tell a body of exactly the limit from
+	// a larger one
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+	n, err := body.ReadCloser.Read(p)
+	body.remaining -= int64(n)
+	if body.remaining < 0 {
+		return n + int(body.remaining), &BodyTooLargeError{Limit: body.limit}
+	}
+	return n, err
+}
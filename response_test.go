@@ -0,0 +1,123 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type streamItem struct {
+	Id int `json:"id"`
+}
+
+func streamResponse(body string) *Response {
+	return &Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	bodies := []string{
+		` [{"id":1},{"id":2},{"id":3}]`,
+		"{\"id\":1}\n{\"id\":2}\n\n{\"id\":3}\n",
+	}
+	for _, body := range bodies {
+		ids := []int{}
+		err := DecodeEach(streamResponse(body), func(item streamItem) error {
+			ids = append(ids, item.Id)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("error decoding %q: %v", body, err)
+		}
+		if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+			t.Fatalf("unexpected items %v from %q", ids, body)
+		}
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err := DecodeEach(streamResponse(bodies[0]), func(item streamItem) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Fatalf("callback error did not stop the stream")
+	}
+}
+
+func TestSaveTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "download.bin")
+	written, err := streamResponse("content").SaveTo(path)
+	if err != nil || written != 7 {
+		t.Fatalf("unexpected result %d %v", written, err)
+	}
+	byts, err := os.ReadFile(path)
+	if err != nil || string(byts) != "content" {
+		t.Fatalf("unexpected file contents %q %v", byts, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("temporary file left behind")
+	}
+
+	created, err := os.Create(filepath.Join(t.TempDir(), "created.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.Close()
+	want, _ := os.Stat(created.Name())
+	got, _ := os.Stat(path)
+	if got.Mode() != want.Mode() {
+		t.Fatalf("saved with mode %v instead of %v", got.Mode(), want.Mode())
+	}
+}
+
+func TestMaxResponseBodySize(t *testing.T) {
+	body := "0123456789"
+	respond := func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: respond,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.MaxResponseBodySize = 10
+	client = client.WithOptions(optn)
+
+	res, err := client.Get(context.TODO(), nil, "http://a.test", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if byts, err := io.ReadAll(res.Body); err != nil || string(byts) != body {
+		t.Fatalf("body at the limit rejected %q %v", byts, err)
+	}
+
+	body = "0123456789a"
+	res, err = client.Get(context.TODO(), nil, "http://a.test", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	_, err = io.ReadAll(res.Body)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 10 {
+		t.Fatalf("expected body too large, got %v", err)
+	}
+}
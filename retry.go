@@ -0,0 +1,248 @@
+package gottp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetryClassifier retries responses with one of the RetriableCodes
+// and, for idempotent requests, transport errors such as connection resets,
+// DNS failures and attempt timeouts
+type DefaultRetryClassifier struct {
+	RetriableCodes []int
+}
+
+func (classifier DefaultRetryClassifier) ShouldRetry(
+	attempt int,
+	req *http.Request,
+	resp *http.Response,
+	err error,
+) bool {
+	if err != nil {
+		return isIdempotent(req)
+	}
+	if resp.StatusCode > 299 {
+		for _, val := range classifier.RetriableCodes {
+			if resp.StatusCode == val {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIdempotent reports whether the request can safely be sent more than once
+// without the server having seen the previous attempt
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+func (client *HttpClient) retryClassifier() RetryClassifier {
+	if client.optn.Retry.Classifier != nil {
+		return client.optn.Retry.Classifier
+	}
+	codes := client.optn.Retry.RetriableCodes
+	if client.optn.Retry.RetryTooManyRequests {
+		codes = append([]int{http.StatusTooManyRequests}, codes...)
+	}
+	return DefaultRetryClassifier{
+		RetriableCodes: codes,
+	}
+}
+
+// retryDelay the time to wait before the next attempt, the delay requested
+// by the server takes precedence over the backoff
+func (client *HttpClient) retryDelay(
+	attempt int,
+	previous time.Duration,
+	resp *http.Response,
+) time.Duration {
+	delay := client.backoff.Backoff(attempt, previous)
+	if limit := client.optn.Retry.MaxBackoff; limit > 0 && delay > limit {
+		delay = limit
+	}
+	if wait, ok := serverRetryDelay(resp, time.Now()); ok {
+		delay = wait
+		limit := client.optn.Retry.MaxRetryAfter
+		if limit > 0 && delay > limit {
+			delay = limit
+		}
+	}
+	return delay
+}
+
+// serverRetryDelay reads the delay requested by the server through the
+// Retry-After header, as seconds or an HTTP-date, or through
+// X-RateLimit-Reset when the response signals the rate limit is exhausted
+func serverRetryDelay(
+	resp *http.Response,
+	now time.Time,
+) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if val := strings.TrimSpace(resp.Header.Get("Retry-After")); val != "" {
+		if secs, err := strconv.ParseInt(val, 10, 64); err == nil {
+			if secs < 0 {
+				return 0, false
+			}
+			return time.Duration(secs) * time.Second, true
+		}
+		if date, err := http.ParseTime(val); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests &&
+		resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	return rateLimitReset(resp, now)
+}
+
+func nonNegative(duration time.Duration) time.Duration {
+	if duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// send runs the attempts of a request through next, applying the bulkhead,
+// the retry policy, the rate limits, the circuit breaker and the configured
+// timeouts, annotating the dependency's trace fields along the way and
+// tracing every attempt as a child span of the dependency
+func (client *HttpClient) send(
+	req *http.Request,
+	next Handler,
+) (*http.Response, error) {
+	ctx := req.Context()
+	fields := traceFields(ctx)
+	octx, cancelOverall := client.overallContext(ctx)
+	release, err := client.bulkheads.acquire(octx, req.URL.Host)
+	if err != nil {
+		closeBody(req)
+		err = wrapContextError(ctx, octx, nil, 0, err)
+		cancelOverall()
+		return nil, err
+	}
+	var actx context.Context
+	var cancelAttempt context.CancelFunc
+	classifier := client.retryClassifier()
+	breaker := client.breakers.get(req.URL.Host)
+
+	var resp *http.Response
+	var delay, waited time.Duration
+	reason := ""
+	attempt := 0
+	start := time.Now()
+	for {
+		actx, cancelAttempt = client.attemptContext(octx)
+		if err = client.limiters.wait(octx, req); err != nil {
+			break
+		}
+		if err = breaker.allow(fields); err != nil {
+			break
+		}
+		var areq *http.Request
+		areq, err = newAttempt(actx, req, attempt)
+		if err != nil {
+			breaker.release()
+			break
+		}
+		areq = client.attemptTrace(areq)
+		astart := time.Now()
+		resp, err = next(areq)
+		attempt++
+		fields["attempts"] = strconv.Itoa(attempt)
+		client.limiters.observe(req, resp)
+		breaker.record(
+			(err != nil && octx.Err() == nil) ||
+				(err == nil && resp.StatusCode > 499),
+			fields,
+		)
+		client.traceAttempt(areq, astart, attempt, waited, reason, resp, err)
+
+		if !client.optn.Retry.Enabled ||
+			attempt > client.optn.Retry.RetryCount ||
+			!canReplay(req) ||
+			!classifier.ShouldRetry(attempt, areq, resp, err) {
+			break
+		}
+		reason = retryReason(resp, err)
+		delay = client.retryDelay(attempt, delay, resp)
+		if client.optn.Retry.MaxElapsed > 0 &&
+			time.Since(start)+delay > client.optn.Retry.MaxElapsed {
+			break
+		}
+
+		discardResponse(resp)
+		resp = nil
+		cancelAttempt()
+		if err = sleepContext(octx, delay); err != nil {
+			break
+		}
+		waited = delay
+	}
+
+	if err != nil {
+		if attempt == 0 {
+			closeBody(req)
+		}
+		err = wrapContextError(ctx, octx, actx, attempt, err)
+		cancelAttempt()
+		cancelOverall()
+		release()
+		return nil, err
+	}
+	resp.Body = releaseOnClose(resp.Body, cancelAttempt, cancelOverall, release)
+	return resp, nil
+}
+
+// retryReason describes why a finished attempt is retried
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "status " + strconv.Itoa(resp.StatusCode)
+}
+
+// maxDiscardDrain bytes read from a discarded response so its connection can
+// be reused, larger bodies are closed without reading them to the end
+const maxDiscardDrain = 4 << 10
+
+// discardResponse drains and closes a response that will not be handed to the
+// caller
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	io.CopyN(io.Discard, resp.Body, maxDiscardDrain)
+	resp.Body.Close()
+}
+
+// sleepContext waits for the duration, returning early with the context's
+// error once it is done
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
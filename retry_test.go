@@ -0,0 +1,275 @@
+package gottp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerRetryDelay(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		status  int
+		headers map[string]string
+		delay   time.Duration
+		ok      bool
+	}{
+		{503, map[string]string{"Retry-After": "3"}, 3 * time.Second, true},
+		{
+			503,
+			map[string]string{
+				"Retry-After": now.Add(5 * time.Second).Format(http.TimeFormat),
+			},
+			5 * time.Second,
+			true,
+		},
+		{429, map[string]string{"X-RateLimit-Reset": "2"}, 2 * time.Second, true},
+		{
+			429,
+			map[string]string{"X-RateLimit-Reset": "1640995210"},
+			10 * time.Second,
+			true,
+		},
+		{500, map[string]string{"X-RateLimit-Reset": "2"}, 0, false},
+		{503, map[string]string{"Retry-After": "soon"}, 0, false},
+		{503, nil, 0, false},
+	}
+	for idx, tc := range cases {
+		resp := &http.Response{
+			StatusCode: tc.status,
+			Header:     http.Header{},
+		}
+		for key, val := range tc.headers {
+			resp.Header.Set(key, val)
+		}
+		delay, ok := serverRetryDelay(resp, now)
+		if delay != tc.delay || ok != tc.ok {
+			t.Errorf("case %d: got %v %v, expected %v %v", idx, delay, ok, tc.delay, tc.ok)
+		}
+	}
+}
+
+func TestRetryAfterTooManyRequests(t *testing.T) {
+	tries := 0
+	limited := func() (*http.Response, error) {
+		tries++
+		if tries < 2 {
+			return &http.Response{
+				StatusCode: 429,
+				Header:     http.Header{"Retry-After": []string{"120"}},
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: limited,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.RetryTooManyRequests = true
+	optn.Retry.InitialBackoff = time.Minute
+	optn.Retry.MaxRetryAfter = 10 * time.Millisecond
+	client = client.WithOptions(optn)
+
+	start := time.Now()
+	res, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 || tries != 2 {
+		t.Fatalf("expected retried success, got %d after %d", res.StatusCode, tries)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("server delay was not capped by MaxRetryAfter")
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	read   bool
+	closed bool
+}
+
+func (body *trackedBody) Read(p []byte) (int, error) {
+	n, err := body.Reader.Read(p)
+	if err == io.EOF {
+		body.read = true
+	}
+	return n, err
+}
+
+func (body *trackedBody) Close() error {
+	body.closed = true
+	return nil
+}
+
+func TestRetryClosesDiscardedResponses(t *testing.T) {
+	bodies := []*trackedBody{}
+	failThrice := func() (*http.Response, error) {
+		body := &trackedBody{Reader: strings.NewReader("error page")}
+		bodies = append(bodies, body)
+		status := 500
+		if len(bodies) > 3 {
+			status = 200
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       body,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: failThrice,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	res, err := client.Get(context.TODO(), nil, "", nil)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 || len(bodies) != 4 {
+		t.Fatalf("expected 4 attempts, got %d", len(bodies))
+	}
+	for idx, body := range bodies[:3] {
+		if !body.read || !body.closed {
+			t.Errorf("discarded body %d not drained and closed", idx)
+		}
+	}
+	if bodies[3].closed {
+		t.Fatalf("final body closed before being returned")
+	}
+	res.Body.Close()
+	if !bodies[3].closed {
+		t.Fatalf("final body not closed through the response")
+	}
+}
+
+func TestRetryClosesResponseWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	body := &trackedBody{Reader: strings.NewReader("error page")}
+	failOnce := func() (*http.Response, error) {
+		cancel()
+		return &http.Response{
+			StatusCode: 503,
+			Body:       body,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	_, err := client.Get(ctx, nil, "", nil)
+	if err == nil {
+		t.Fatalf("expected cancellation error")
+	}
+	if !body.closed {
+		t.Fatalf("discarded body not closed")
+	}
+}
+
+type tracedSpan struct {
+	spanId string
+	fields map[string]string
+}
+
+type recordingTrace struct {
+	staticTrace
+	mtx   sync.Mutex
+	spans []tracedSpan
+}
+
+func (m *recordingTrace) TraceDependency(
+	ctx context.Context,
+	spanId string,
+	dependencyType string,
+	serviceName string,
+	commandName string,
+	success bool,
+	startTimestamp time.Time,
+	eventTimestamp time.Time,
+	fields map[string]string,
+) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.spans = append(m.spans, tracedSpan{spanId: spanId, fields: fields})
+}
+
+func TestAttemptSpans(t *testing.T) {
+	traceparents := []string{}
+	tracer := &recordingTrace{}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				traceparents = append(traceparents, req.Header.Get("traceparent"))
+				if len(traceparents) == 1 {
+					return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.Backoff = ConstantBackoff
+	optn.Retry.InitialBackoff = 5 * time.Millisecond
+	client = client.WithOptions(optn)
+	if _, err := client.Get(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("expected 2 attempt spans and the call span, got %v", tracer.spans)
+	}
+	first, second, call := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if call.fields["attempts"] != "2" || call.fields["attempt"] != "" {
+		t.Fatalf("unexpected call span fields %v", call.fields)
+	}
+	for idx, span := range []tracedSpan{first, second} {
+		if span.spanId == call.spanId ||
+			span.fields["parentSpanId"] != call.spanId ||
+			!strings.Contains(traceparents[idx], "-"+span.spanId+"-") {
+			t.Fatalf("attempt %d span %v does not match %q", idx+1, span, traceparents[idx])
+		}
+	}
+	if first.fields["attempt"] != "1" || first.fields["backoff"] != "" {
+		t.Fatalf("unexpected first attempt fields %v", first.fields)
+	}
+	if second.fields["attempt"] != "2" ||
+		second.fields["backoff"] != "5ms" ||
+		second.fields["retryReason"] != "status 503" {
+		t.Fatalf("unexpected second attempt fields %v", second.fields)
+	}
+}
@@ -0,0 +1,291 @@
+package gottp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+// Event server-sent event, Id is the last event id seen on the stream when
+// the event was dispatched
+type Event struct {
+	Id    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// EventStream subscription to a text/event-stream endpoint
+type EventStream struct {
+	events chan Event
+	cancel context.CancelFunc
+	err    error
+}
+
+// Events emits the events of the stream, the channel is closed once the
+// subscription ends
+func (stream *EventStream) Events() <-chan Event {
+	return stream.events
+}
+
+// Err the reason the subscription ended, only valid once Events is closed,
+// nil when the server ended it with 204 No Content
+func (stream *EventStream) Err() error {
+	return stream.err
+}
+
+// Close ends the subscription
+func (stream *EventStream) Close() {
+	stream.cancel()
+}
+
+// Subscribe connects to a server-sent events endpoint and emits its events
+// until the context is done, Close is called or reconnecting fails.
+// Dropped connections are re-established with the Last-Event-ID header,
+// waiting the delay requested by the server or the client's backoff, and
+// give up after RetryCount consecutive failed connections. Every connection
+// is traced as an "sse" dependency spanning its lifetime, a child of the
+// connection's http call.
+func (client *HttpClient) Subscribe(
+	ctx context.Context,
+	headers map[string]string,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) *EventStream {
+	ctx, cancel := context.WithCancel(ctx)
+	stream := &EventStream{
+		events: make(chan Event),
+		cancel: cancel,
+	}
+	go stream.run(
+		ctx,
+		client,
+		client.NewRequest("GET", endpoint).
+			Headers(headers).
+			QueryValues(qParam).
+			PathParams(params...),
+	)
+	return stream
+}
+
+// streamingClient the client without the policies that assume short lived
+// responses, timeouts would cut the stream and reconnecting replaces retries
+func (client *HttpClient) streamingClient() *HttpClient {
+	clone := *client
+	optn := *client.optn
+	optn.Timeout = 0
+	optn.OverallDeadline = 0
+	optn.Retry.Enabled = false
+	optn.Hedging.Enabled = false
+	optn.ErrorOnStatus = false
+	optn.MaxResponseBodySize = 0
+	clone.optn = &optn
+	return &clone
+}
+
+func (stream *EventStream) run(
+	ctx context.Context,
+	client *HttpClient,
+	builder *RequestBuilder,
+) {
+	defer close(stream.events)
+	defer stream.cancel()
+	builder.client = client.streamingClient()
+	builder.Header("Accept", "text/event-stream")
+	builder.Header("Cache-Control", "no-cache")
+
+	state := &eventState{}
+	failures := 0
+	var delay time.Duration
+	for {
+		if state.lastId != "" {
+			builder.Header("Last-Event-ID", state.lastId)
+		} else {
+			delete(builder.headers, "Last-Event-ID")
+		}
+		start := time.Now()
+		received := 0
+		retriable := true
+		call := &callTrace{}
+		resp, err := builder.Do(context.WithValue(ctx, callTraceKey{}, call))
+		if err == nil {
+			switch {
+			case resp.StatusCode == 204:
+				resp.Body.Close()
+				stream.trace(ctx, client, call, start, 0, nil)
+				return
+			case resp.StatusCode > 199 && resp.StatusCode < 300:
+				received, err = stream.read(ctx, resp.Body, state)
+			default:
+				retriable = client.retryClassifier().ShouldRetry(
+					failures+1,
+					resp.Request,
+					(*http.Response)(resp),
+					nil,
+				)
+				discardResponse((*http.Response)(resp))
+				err = &StatusError{StatusCode: resp.StatusCode}
+			}
+		}
+		stream.trace(ctx, client, call, start, received, err)
+
+		if ctx.Err() != nil {
+			stream.err = ctx.Err()
+			return
+		}
+		if received > 0 {
+			failures = 0
+			delay = 0
+		}
+		failures++
+		if !retriable ||
+			!client.optn.Retry.Enabled ||
+			failures > client.optn.Retry.RetryCount {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			stream.err = err
+			return
+		}
+
+		delay = client.backoff.Backoff(failures, delay)
+		if state.retry > 0 {
+			delay = state.retry
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			stream.err = err
+			return
+		}
+	}
+}
+
+// eventState parser state kept across connections
+type eventState struct {
+	lastId string
+	retry  time.Duration
+}
+
+// read parses the stream, emitting every complete event, until the
+// connection ends
+func (stream *EventStream) read(
+	ctx context.Context,
+	body io.ReadCloser,
+	state *eventState,
+) (int, error) {
+	defer body.Close()
+	reader := bufio.NewReader(body)
+	received := 0
+	event := Event{}
+	var data strings.Builder
+	// the id only becomes the last event id once its event is dispatched, so
+	// a connection dropped mid event resumes from the previous one
+	id := state.lastId
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return received, nil
+			}
+			return received, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			state.lastId = id
+			if data.Len() == 0 {
+				event = Event{}
+				continue
+			}
+			event.Id = state.lastId
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			if event.Event == "" {
+				event.Event = "message"
+			}
+			select {
+			case stream.events <- event:
+				received++
+			case <-ctx.Done():
+				return received, ctx.Err()
+			}
+			event = Event{}
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if idx := strings.IndexByte(line, ':'); idx >= 0 {
+			field, value = line[:idx], strings.TrimPrefix(line[idx+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				id = value
+			}
+		case "retry":
+			if millis, err := strconv.ParseUint(value, 10, 63); err == nil {
+				state.retry = time.Duration(millis) * time.Millisecond
+				event.Retry = state.retry
+			}
+		}
+	}
+}
+
+// trace the lifetime of a connection as a child of its call's span, nothing
+// is traced when the connection failed before the call was traced
+func (stream *EventStream) trace(
+	ctx context.Context,
+	client *HttpClient,
+	call *callTrace,
+	start time.Time,
+	received int,
+	err error,
+) {
+	if call.trace.SpanId == "" {
+		return
+	}
+	sid, gerr := hlpr.GenerateParentId()
+	if gerr != nil {
+		return
+	}
+	fields := map[string]string{
+		"method":       "GET",
+		"url":          call.url,
+		"events":       strconv.Itoa(received),
+		"parentSpanId": call.trace.SpanId,
+	}
+	if call.root {
+		setRootFields(call.trace, fields)
+	}
+	success := err == nil || errors.Is(err, context.Canceled)
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	client.tracer.TraceDependency(
+		ctx,
+		sid,
+		"sse",
+		call.host,
+		fmt.Sprintf("GET %s", call.uri),
+		success,
+		start,
+		time.Now(),
+		fields,
+	)
+}
@@ -0,0 +1,270 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dependencyTrace struct {
+	MockTrace
+	mtx    sync.Mutex
+	fields []map[string]string
+	calls  []string
+}
+
+func (m *dependencyTrace) TraceDependency(
+	ctx context.Context,
+	spanId string,
+	dependencyType string,
+	serviceName string,
+	commandName string,
+	success bool,
+	startTimestamp time.Time,
+	eventTimestamp time.Time,
+	fields map[string]string,
+) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if dependencyType != "sse" {
+		if _, ok := fields["attempt"]; !ok {
+			m.calls = append(m.calls, spanId)
+		}
+		return
+	}
+	m.fields = append(m.fields, fields)
+}
+
+func TestSubscribe(t *testing.T) {
+	connections := 0
+	lastIds := []string{}
+	tracer := &dependencyTrace{}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				connections++
+				lastIds = append(lastIds, req.Header.Get("Last-Event-ID"))
+				if connections > 1 {
+					return &http.Response{StatusCode: 204, Body: http.NoBody}, nil
+				}
+				body := ": keep-alive\n" +
+					"retry: 1\n" +
+					"id: 1\n" +
+					"data: first\n" +
+					"data: line\n\n" +
+					"event: update\r\n" +
+					"id: 2\r\n" +
+					"data:second\r\n\r\n" +
+					"data: incomplete"
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader(body)),
+				}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+
+	stream := client.Subscribe(context.TODO(), nil, "http://events.test/feed", nil)
+	events := []Event{}
+	for event := range stream.Events() {
+		events = append(events, event)
+	}
+	if stream.Err() != nil {
+		t.Fatalf("unexpected error: %v", stream.Err())
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	if events[0].Id != "1" || events[0].Event != "message" ||
+		events[0].Data != "first\nline" || events[0].Retry != time.Millisecond {
+		t.Fatalf("unexpected first event %+v", events[0])
+	}
+	if events[1].Id != "2" || events[1].Event != "update" ||
+		events[1].Data != "second" {
+		t.Fatalf("unexpected second event %+v", events[1])
+	}
+	if connections != 2 || lastIds[0] != "" || lastIds[1] != "2" {
+		t.Fatalf("unexpected reconnects %d %v", connections, lastIds)
+	}
+	if len(tracer.fields) != 2 || tracer.fields[0]["events"] != "2" {
+		t.Fatalf("unexpected connection traces %v", tracer.fields)
+	}
+	if len(tracer.calls) != 2 {
+		t.Fatalf("unexpected call traces %v", tracer.calls)
+	}
+	for idx, fields := range tracer.fields {
+		if fields["parentSpanId"] != tracer.calls[idx] {
+			t.Errorf(
+				"connection %d traced under %s instead of its call %s",
+				idx,
+				fields["parentSpanId"],
+				tracer.calls[idx],
+			)
+		}
+	}
+}
+
+func TestSubscribeGivesUp(t *testing.T) {
+	connections := 0
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: func() (*http.Response, error) {
+				connections++
+				return &http.Response{StatusCode: 404, Body: http.NoBody}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	stream := client.Subscribe(context.TODO(), nil, "", nil)
+	for range stream.Events() {
+	}
+	var statusErr *StatusError
+	if !errors.As(stream.Err(), &statusErr) || statusErr.StatusCode != 404 {
+		t.Fatalf("expected status error, got %v", stream.Err())
+	}
+	if connections != 1 {
+		t.Fatalf("expected no reconnect, got %d connections", connections)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	client = NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: func() (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	stream = client.Subscribe(ctx, nil, "", nil)
+	cancel()
+	for range stream.Events() {
+	}
+	if !errors.Is(stream.Err(), context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", stream.Err())
+	}
+}
+
+func TestSubscribeResetsLastEventId(t *testing.T) {
+	lastIds := []string{}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				lastIds = append(lastIds, req.Header.Get("Last-Event-ID"))
+				switch len(lastIds) {
+				case 1:
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader("retry: 1\nid: 1\ndata: a\n\n")),
+					}, nil
+				case 2:
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader("id\ndata: b\n\n")),
+					}, nil
+				}
+				return &http.Response{StatusCode: 204, Body: http.NoBody}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	stream := client.Subscribe(context.TODO(), nil, "", nil)
+	events := []Event{}
+	for event := range stream.Events() {
+		events = append(events, event)
+	}
+	if len(events) != 2 || events[1].Id != "" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if len(lastIds) != 3 || lastIds[1] != "1" || lastIds[2] != "" {
+		t.Fatalf("reset last event id was still sent %v", lastIds)
+	}
+}
+
+func TestSubscribeKeepsIdOfDroppedEvent(t *testing.T) {
+	lastIds := []string{}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				lastIds = append(lastIds, req.Header.Get("Last-Event-ID"))
+				if len(lastIds) > 1 {
+					return &http.Response{StatusCode: 204, Body: http.NoBody}, nil
+				}
+				body := "retry: 1\n" +
+					"id: 1\ndata: one\n\n" +
+					"id: 2\ndata: two-partial\n"
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader(body)),
+				}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	stream := client.Subscribe(context.TODO(), nil, "", nil)
+	events := []Event{}
+	for event := range stream.Events() {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Id != "1" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if len(lastIds) != 2 || lastIds[1] != "1" {
+		t.Fatalf("id of the undispatched event was sent %v", lastIds)
+	}
+}
+
+func TestSubscribeReconnectsThroughClassifier(t *testing.T) {
+	connections := 0
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: func() (*http.Response, error) {
+				connections++
+				if connections > 1 {
+					return &http.Response{StatusCode: 204, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: 429, Body: http.NoBody}, nil
+			},
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	optn.Retry.RetryTooManyRequests = true
+	client = client.WithOptions(optn)
+	stream := client.Subscribe(context.TODO(), nil, "", nil)
+	for range stream.Events() {
+	}
+	if stream.Err() != nil || connections != 2 {
+		t.Fatalf("429 not reconnected, %d connections %v", connections, stream.Err())
+	}
+}
@@ -0,0 +1,100 @@
+package gottp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// StreamBody raw request body read from a reader, for blobs, NDJSON and
+// other payloads that should not be marshalled in memory
+type StreamBody struct {
+	// Reader the body, closed once the call is done when it is an io.Closer
+	Reader      io.Reader
+	ContentType string
+	// Length of the body in bytes, -1 sends it with chunked transfer
+	// encoding and zero detects the length of *bytes.Buffer, *bytes.Reader
+	// and *strings.Reader readers and sends any other reader chunked
+	Length int64
+	// Rewind provides the body again for a retry, readers implementing
+	// io.Seeker are rewound without it and the request is not retried when
+	// neither is available
+	Rewind func() (io.Reader, error)
+}
+
+// length the content length of the body, -1 when unknown
+func (body StreamBody) length() int64 {
+	if body.Length != 0 {
+		return body.Length
+	}
+	switch reader := body.Reader.(type) {
+	case nil:
+		return 0
+	case *bytes.Buffer:
+		return int64(reader.Len())
+	case *bytes.Reader:
+		return int64(reader.Len())
+	case *strings.Reader:
+		return int64(reader.Len())
+	}
+	return -1
+}
+
+// replayable reports whether the body can be provided again for a retry
+func (body StreamBody) replayable() bool {
+	if body.Rewind != nil {
+		return true
+	}
+	_, ok := body.Reader.(io.Seeker)
+	return ok
+}
+
+// factory hands out the reader for the first attempt and rewinds it for
+// every following one. The transport closes the body of every attempt, so it
+// only gets the readers without their Close and the returned func closes them
+// once the call is done.
+func (body StreamBody) factory() (bodyFactory, func()) {
+	var mtx sync.Mutex
+	var invocations int
+	var start int64
+	if seeker, ok := body.Reader.(io.Seeker); ok && body.Rewind == nil {
+		start, _ = seeker.Seek(0, io.SeekCurrent)
+	}
+	readers := []io.Reader{body.Reader}
+	factory := func() (io.ReadCloser, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reader := body.Reader
+		invocations++
+		if invocations > 1 {
+			if body.Rewind != nil {
+				var err error
+				if reader, err = body.Rewind(); err != nil {
+					return nil, err
+				}
+				readers = append(readers, reader)
+			} else if seeker, ok := reader.(io.Seeker); ok {
+				if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+					return nil, err
+				}
+			} else {
+				return nil, ErrBodyNotReplayable
+			}
+		}
+		return io.NopCloser(reader), nil
+	}
+	var once sync.Once
+	closeReaders := func() {
+		once.Do(func() {
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, reader := range readers {
+				if closer, ok := reader.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		})
+	}
+	return factory, closeReaders
+}
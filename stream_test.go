@@ -0,0 +1,212 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostStreamRewind(t *testing.T) {
+	bodies := []string{}
+	lengths := []int64{}
+	failOnce := func(req *http.Request) (*http.Response, error) {
+		byts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(byts))
+		lengths = append(lengths, req.ContentLength)
+		if req.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected content type %s", req.Header.Get("Content-Type"))
+		}
+		status := 200
+		if len(bodies) < 2 {
+			status = 502
+		}
+		return &http.Response{
+			StatusCode: status,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	payload := "{\"a\":1}\n{\"a\":2}\n"
+	rewinds := 0
+	res, err := client.PostStream(
+		context.TODO(),
+		nil,
+		StreamBody{
+			Reader:      io.MultiReader(strings.NewReader(payload)),
+			ContentType: "application/x-ndjson",
+			Length:      -1,
+			Rewind: func() (io.Reader, error) {
+				rewinds++
+				return strings.NewReader(payload), nil
+			},
+		},
+		"http://a.test/ingest",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 200 || len(bodies) != 2 || rewinds != 1 {
+		t.Fatalf("expected one rewound retry, got %d attempts", len(bodies))
+	}
+	for idx := range bodies {
+		if bodies[idx] != payload || lengths[idx] != -1 {
+			t.Errorf("attempt %d sent %q with length %d", idx, bodies[idx], lengths[idx])
+		}
+	}
+}
+
+func TestPutStreamSeekable(t *testing.T) {
+	bodies := []string{}
+	lengths := []int64{}
+	failOnce := func(req *http.Request) (*http.Response, error) {
+		byts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(byts))
+		lengths = append(lengths, req.ContentLength)
+		status := 200
+		if len(bodies) < 2 {
+			status = 500
+		}
+		return &http.Response{
+			StatusCode: status,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: failOnce,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	_, err := client.PutStream(
+		context.TODO(),
+		nil,
+		StreamBody{Reader: strings.NewReader("blob")},
+		"http://a.test/blob",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if len(bodies) != 2 || bodies[1] != "blob" || lengths[1] != 4 {
+		t.Fatalf("seekable body not rewound %q %v", bodies, lengths)
+	}
+}
+
+func TestStreamNotRetriedWithoutRewind(t *testing.T) {
+	tries := 0
+	unavailable := func(req *http.Request) (*http.Response, error) {
+		tries++
+		io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: 503,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: unavailable,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	res, err := client.NewRequest("PUT", "http://a.test/blob").
+		Body(io.MultiReader(strings.NewReader("blob"))).
+		Do(context.TODO())
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if res.StatusCode != 503 || tries != 1 {
+		t.Fatalf("non seekable body retried, %d attempts", tries)
+	}
+}
+
+func TestPutStreamFileRetried(t *testing.T) {
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			byts, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(byts))
+			if len(bodies) < 2 {
+				w.WriteHeader(503)
+			}
+		},
+	))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewHttpClientWithClientProvider(
+		server.Client(),
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	optn := DefaultOptions()
+	optn.Retry.InitialBackoff = time.Millisecond
+	client = client.WithOptions(optn)
+
+	res, err := client.PutStream(
+		context.TODO(),
+		nil,
+		StreamBody{Reader: file, Length: 5},
+		server.URL+"/blob",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 || len(bodies) != 2 || bodies[1] != "hello" {
+		t.Fatalf("file body not retried, %d %q", res.StatusCode, bodies)
+	}
+	if _, err := file.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("file not closed once the call finished, %v", err)
+	}
+}
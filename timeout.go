@@ -0,0 +1,86 @@
+package gottp
+
+import (
+	"context"
+	"io"
+)
+
+// overallContext bounds the context by the overall deadline covering every
+// attempt and backoff of a request
+func (client *HttpClient) overallContext(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	if client.optn.OverallDeadline > 0 {
+		return context.WithTimeout(ctx, client.optn.OverallDeadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+// attemptContext bounds the context by the timeout of a single attempt
+func (client *HttpClient) attemptContext(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	if client.optn.Timeout > 0 {
+		return context.WithTimeout(ctx, client.optn.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// wrapContextError converts an error caused by one of the request's contexts
+// into a ContextError identifying which of them aborted the request
+func wrapContextError(
+	caller context.Context,
+	overall context.Context,
+	attempt context.Context,
+	attempts int,
+	err error,
+) error {
+	var reason error
+	switch {
+	case caller.Err() != nil:
+		reason = ErrCanceled
+	case overall.Err() != nil:
+		reason = ErrDeadlineExceeded
+	case attempt != nil && attempt.Err() != nil:
+		reason = ErrAttemptTimeout
+	default:
+		return err
+	}
+	return &ContextError{
+		Reason:   reason,
+		Attempts: attempts,
+		Err:      err,
+	}
+}
+
+// cancelOnClose releases the request's contexts and resources once the
+// response body has been closed, they have to outlive the call so the body
+// can still be read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancels []func()
+}
+
+func (body *cancelOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	for _, cancel := range body.cancels {
+		cancel()
+	}
+	return err
+}
+
+func releaseOnClose(
+	body io.ReadCloser,
+	cancels ...func(),
+) io.ReadCloser {
+	if body == nil {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		return nil
+	}
+	return &cancelOnClose{
+		ReadCloser: body,
+		cancels:    cancels,
+	}
+}
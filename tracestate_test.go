@@ -0,0 +1,117 @@
+package gottp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	hlpr "github.com/BetaLixT/gottp/helpers"
+)
+
+func TestParseTracestate(t *testing.T) {
+	state, err := hlpr.ParseTracestate(
+		"congo=t61rcWkgMzE, ,rojo=00f067aa0ba902b7",
+		"tenant@vendor=a b",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.String() != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7,tenant@vendor=a b" {
+		t.Fatalf("unexpected serialization %q", state.String())
+	}
+
+	invalid := []string{
+		"Upper=1",
+		"1key=1",
+		"key",
+		"key=",
+		"key=caf\u00e9",
+		"key=a=b",
+		"tenant@=1",
+		"tenant@toolongsystemid=1",
+		"a=1,a=2",
+	}
+	for _, header := range invalid {
+		if _, err := hlpr.ParseTracestate(header); err == nil {
+			t.Fatalf("expected %q to be rejected", header)
+		}
+	}
+
+	members := make([]string, 33)
+	for idx := range members {
+		members[idx] = fmt.Sprintf("k%d=v", idx)
+	}
+	if _, err := hlpr.ParseTracestate(strings.Join(members, ",")); err == nil {
+		t.Fatalf("expected more than 32 members to be rejected")
+	}
+	state, err = hlpr.ParseTracestate(strings.Join(members[:32], ","))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state, err = state.Set("new", "v")
+	if err != nil || len(state) != 32 || state[0].Key != "new" ||
+		state[31].Key != "k30" {
+		t.Fatalf("expected rightmost member dropped, got %v %v", state, err)
+	}
+}
+
+func TestTracestateMutation(t *testing.T) {
+	state, _ := hlpr.ParseTracestate("a=1,b=2,c=3")
+	state, err := state.Set("b", "updated")
+	if err != nil || state.String() != "b=updated,a=1,c=3" {
+		t.Fatalf("expected updated key moved first, got %q %v", state, err)
+	}
+	if value, ok := state.Get("c"); !ok || value != "3" {
+		t.Fatalf("unexpected value %q", value)
+	}
+	if state.Delete("a").String() != "b=updated,c=3" {
+		t.Fatalf("unexpected delete result %q", state.Delete("a"))
+	}
+	if _, err := state.Set("B", "1"); err == nil {
+		t.Fatalf("expected invalid key to be rejected")
+	}
+}
+
+type tracestateTrace struct {
+	MockTrace
+	state string
+}
+
+func (m *tracestateTrace) ExtractTraceState(ctx context.Context) string {
+	return m.state
+}
+
+func TestTracestatePropagation(t *testing.T) {
+	received := ""
+	tracer := &tracestateTrace{state: "congo=t61rcWkgMzE,, rojo=00f067aa0ba902b7"}
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: func(req *http.Request) (*http.Response, error) {
+				received = req.Header.Get("tracestate")
+				return &http.Response{StatusCode: 200}, nil
+			},
+		},
+		tracer,
+		nil,
+		"",
+		"",
+		"",
+	)
+	if _, err := client.Get(context.TODO(), nil, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if received != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Fatalf("unexpected tracestate %q", received)
+	}
+
+	tracer.state = "invalid"
+	headers := map[string]string{"tracestate": "stale=1"}
+	if _, err := client.Get(context.TODO(), headers, "", nil); err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if received != "" {
+		t.Fatalf("expected malformed tracestate dropped, got %q", received)
+	}
+}
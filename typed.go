@@ -0,0 +1,220 @@
+package gottp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ErrorDecoder converts the body of a non 2xx response into the error
+// returned by the typed helpers
+type ErrorDecoder func(resp *Response, body []byte) error
+
+// StatusError returned by the typed helpers for non 2xx responses when no
+// ErrorDecoder is configured, Problem is set for application/problem+json
+// responses
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	Problem    *ProblemDetails
+}
+
+func (err *StatusError) Error() string {
+	if err.Problem != nil {
+		return fmt.Sprintf(
+			"unexpected status code %d: %s",
+			err.StatusCode,
+			err.Problem.Error(),
+		)
+	}
+	return fmt.Sprintf("unexpected status code %d", err.StatusCode)
+}
+
+func (err *StatusError) Unwrap() error {
+	if err.Problem == nil {
+		return nil
+	}
+	return err.Problem
+}
+
+// APIError non 2xx response whose body was decoded by JSONErrorDecoder
+type APIError[E any] struct {
+	StatusCode int
+	Body       E
+}
+
+func (err *APIError[E]) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %+v", err.StatusCode, err.Body)
+}
+
+// JSONErrorDecoder decodes error bodies into E, returning an *APIError[E],
+// bodies that do not decode are reported as a *StatusError
+func JSONErrorDecoder[E any]() ErrorDecoder {
+	return func(resp *Response, body []byte) error {
+		var decoded E
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return &StatusError{
+				StatusCode: resp.StatusCode,
+				Body:       body,
+				Problem:    parseProblem(resp.Header, body),
+			}
+		}
+		return &APIError[E]{StatusCode: resp.StatusCode, Body: decoded}
+	}
+}
+
+// GetJSON HTTP GET method decoding the JSON response into T
+func GetJSON[T any](
+	ctx context.Context,
+	client *HttpClient,
+	headers map[string]string,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (T, *Response, error) {
+	resp, err := client.action(ctx, "GET", headers, endpoint, qParam, params...)
+	return decodeJSON[T](client, resp, err)
+}
+
+// DeleteJSON HTTP DELETE method decoding the JSON response into T
+func DeleteJSON[T any](
+	ctx context.Context,
+	client *HttpClient,
+	headers map[string]string,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (T, *Response, error) {
+	resp, err := client.action(ctx, "DELETE", headers, endpoint, qParam, params...)
+	return decodeJSON[T](client, resp, err)
+}
+
+// PostJSON HTTP POST method with a JSON body decoding the JSON response into
+// Res
+func PostJSON[Req any, Res any](
+	ctx context.Context,
+	client *HttpClient,
+	headers map[string]string,
+	body Req,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (Res, *Response, error) {
+	resp, err := client.actionBody(
+		ctx,
+		"POST",
+		headers,
+		jsonDTO(body),
+		endpoint,
+		qParam,
+		params...,
+	)
+	return decodeJSON[Res](client, resp, err)
+}
+
+// PutJSON HTTP PUT method with a JSON body decoding the JSON response into
+// Res
+func PutJSON[Req any, Res any](
+	ctx context.Context,
+	client *HttpClient,
+	headers map[string]string,
+	body Req,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (Res, *Response, error) {
+	resp, err := client.actionBody(
+		ctx,
+		"PUT",
+		headers,
+		jsonDTO(body),
+		endpoint,
+		qParam,
+		params...,
+	)
+	return decodeJSON[Res](client, resp, err)
+}
+
+// PatchJSON HTTP PATCH method with a JSON body decoding the JSON response
+// into Res
+func PatchJSON[Req any, Res any](
+	ctx context.Context,
+	client *HttpClient,
+	headers map[string]string,
+	body Req,
+	endpoint string,
+	qParam map[string][]string,
+	params ...string,
+) (Res, *Response, error) {
+	resp, err := client.actionBody(
+		ctx,
+		"PATCH",
+		headers,
+		jsonDTO(body),
+		endpoint,
+		qParam,
+		params...,
+	)
+	return decodeJSON[Res](client, resp, err)
+}
+
+// decodeJSON reads and closes the body, decoding it into T for 2xx responses
+// and into an error through the client's ErrorDecoder otherwise
+func decodeJSON[T any](
+	client *HttpClient,
+	resp *Response,
+	err error,
+) (T, *Response, error) {
+	var result T
+	if err != nil {
+		return result, resp, err
+	}
+	var body []byte
+	if resp.Body != nil {
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return result, resp, err
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		decoder := client.optn.ErrorDecoder
+		if decoder == nil {
+			return result, resp, &StatusError{
+				StatusCode: resp.StatusCode,
+				Body:       body,
+				Problem:    parseProblem(resp.Header, body),
+			}
+		}
+		return result, resp, decoder(resp, body)
+	}
+	if len(body) == 0 {
+		return result, resp, nil
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return result, resp, err
+	}
+	return result, resp, nil
+}
+
+// jsonValue adapts any value to IJsonDTO through encoding/json
+type jsonValue struct {
+	value interface{}
+}
+
+func (val jsonValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(val.value)
+}
+
+func (val jsonValue) UnmarshalJSON(byts []byte) error {
+	return json.Unmarshal(byts, val.value)
+}
+
+func jsonDTO(value interface{}) IJsonDTO {
+	if dto, ok := value.(IJsonDTO); ok {
+		return dto
+	}
+	return jsonValue{value: value}
+}
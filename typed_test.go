@@ -0,0 +1,100 @@
+package gottp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type typedItem struct {
+	Name string `json:"name"`
+}
+
+type typedProblem struct {
+	Code string `json:"code"`
+}
+
+func TestPostJSON(t *testing.T) {
+	var sent string
+	echo := func(req *http.Request) (*http.Response, error) {
+		byts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		sent = string(byts)
+		return &http.Response{
+			StatusCode: 201,
+			Body:       io.NopCloser(strings.NewReader(`{"name":"created"}`)),
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			handler: echo,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	item, res, err := PostJSON[typedItem, typedItem](
+		context.TODO(),
+		client,
+		nil,
+		typedItem{Name: "new"},
+		"http://a.test/items",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error encountered making request: %v", err)
+	}
+	if sent != `{"name":"new"}` {
+		t.Fatalf("unexpected request body %s", sent)
+	}
+	if res.StatusCode != 201 || item.Name != "created" {
+		t.Fatalf("unexpected result %d %+v", res.StatusCode, item)
+	}
+}
+
+func TestGetJSONDecodesErrors(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader(`{"code":"missing"}`)}
+	notFound := func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 404,
+			Body:       body,
+		}, nil
+	}
+
+	client := NewHttpClientWithClientProvider(
+		&MockClient{
+			resp: notFound,
+		},
+		&MockTrace{},
+		nil,
+		"",
+		"",
+		"",
+	)
+	_, _, err := GetJSON[typedItem](context.TODO(), client, nil, "http://a.test", nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 404 {
+		t.Fatalf("expected status error, got %v", err)
+	}
+	if !body.closed {
+		t.Fatalf("response body not closed")
+	}
+
+	optn := DefaultOptions()
+	optn.ErrorDecoder = JSONErrorDecoder[typedProblem]()
+	client = client.WithOptions(optn)
+	body.Reader = strings.NewReader(`{"code":"missing"}`)
+	_, _, err = GetJSON[typedItem](context.TODO(), client, nil, "http://a.test", nil)
+	var apiErr *APIError[typedProblem]
+	if !errors.As(err, &apiErr) || apiErr.Body.Code != "missing" {
+		t.Fatalf("expected decoded error, got %v", err)
+	}
+}